@@ -0,0 +1,195 @@
+// Package diff formats the edits of a replacer as a unified diff
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/aswinkarthik/replace-text/replacer"
+)
+
+// DefaultContext is the number of unchanged lines shown around changes
+const DefaultContext = 3
+
+// change replaces the lines [start, end) of the original with lines
+type change struct {
+	start, end int
+	lines      [][]byte
+}
+
+// hunk is a group of changes close enough to share their context lines
+type hunk struct {
+	changes []change
+}
+
+// Unified writes the edits of a file as a unified diff with the given number
+// of context lines around every change. The header names the original file
+// as from and the changed file as to, which are like a/path and b/path
+// for the diff to be applied with patch -p1 or git apply.
+// Nothing is written if there are no changes.
+func Unified(w io.Writer, from, to string, original []byte, edits []replacer.Edit, context int) error {
+	hunks := formatHunks(original, edits, context)
+	if len(hunks) == 0 {
+		return nil
+	}
+
+	if _, err := fmt.Fprintf(w, "--- %s\n+++ %s\n%s", from, to, hunks); err != nil {
+		return fmt.Errorf("error writing diff: %v", err)
+	}
+
+	return nil
+}
+
+// Hunks writes the hunks of the unified diff of the edits, without the header
+// naming the file. Nothing is written if there are no changes.
+func Hunks(w io.Writer, original []byte, edits []replacer.Edit, context int) error {
+	if _, err := w.Write(formatHunks(original, edits, context)); err != nil {
+		return fmt.Errorf("error writing diff: %v", err)
+	}
+
+	return nil
+}
+
+func formatHunks(original []byte, edits []replacer.Edit, context int) []byte {
+	lines := splitLines(original)
+	var out bytes.Buffer
+
+	// offset is the difference of line numbers between the new and the original file
+	offset := 0
+	for _, h := range hunksOf(changesOf(original, lines, edits), context) {
+		first, last := h.changes[0], h.changes[len(h.changes)-1]
+		start := max(first.start-context, 0)
+		end := min(last.end+context, len(lines))
+
+		oldCount, newCount := end-start, end-start
+		for _, c := range h.changes {
+			newCount += len(c.lines) - (c.end - c.start)
+		}
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(start, oldCount), hunkRange(start+offset, newCount))
+
+		at := start
+		for _, c := range h.changes {
+			writeLines(&out, ' ', lines[at:c.start])
+			writeLines(&out, '-', lines[c.start:c.end])
+			writeLines(&out, '+', c.lines)
+			at = c.end
+		}
+		writeLines(&out, ' ', lines[at:end])
+
+		offset += newCount - oldCount
+	}
+
+	return out.Bytes()
+}
+
+// splitLines splits data into lines keeping their line endings
+func splitLines(data []byte) [][]byte {
+	var lines [][]byte
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n') + 1
+		if end == 0 {
+			end = len(data)
+		}
+		lines = append(lines, data[:end])
+		data = data[end:]
+	}
+
+	return lines
+}
+
+// changesOf groups edits touching the same lines into changes of whole lines
+func changesOf(original []byte, lines [][]byte, edits []replacer.Edit) []change {
+	offsets := make([]int64, len(lines)+1)
+	for i, line := range lines {
+		offsets[i+1] = offsets[i] + int64(len(line))
+	}
+
+	// lineAt returns the index of the line holding the byte at offset
+	lineAt := func(offset int64) int {
+		return sort.Search(len(lines), func(i int) bool {
+			return offsets[i+1] > offset
+		})
+	}
+
+	var changes []change
+	for i := 0; i < len(edits); {
+		start := lineAt(edits[i].Start)
+		end := start
+
+		var replaced bytes.Buffer
+		from := offsets[start]
+		for ; i < len(edits) && lineAt(edits[i].Start) <= end; i++ {
+			replaced.Write(original[from:edits[i].Start])
+			replaced.WriteString(edits[i].Replacement)
+			from = edits[i].End
+			if last := lineAt(edits[i].End - 1); last > end {
+				end = last
+			}
+		}
+		replaced.Write(original[from:offsets[end+1]])
+
+		if bytes.Equal(replaced.Bytes(), original[offsets[start]:offsets[end+1]]) {
+			continue
+		}
+		changes = append(changes, change{start: start, end: end + 1, lines: splitLines(replaced.Bytes())})
+	}
+
+	return changes
+}
+
+// hunksOf groups changes whose context lines overlap or touch
+func hunksOf(changes []change, context int) []hunk {
+	var hunks []hunk
+	for _, c := range changes {
+		if n := len(hunks); n > 0 {
+			last := hunks[n-1].changes
+			if c.start-last[len(last)-1].end <= 2*context {
+				hunks[n-1].changes = append(hunks[n-1].changes, c)
+				continue
+			}
+		}
+		hunks = append(hunks, hunk{changes: []change{c}})
+	}
+
+	return hunks
+}
+
+// hunkRange formats the one based start line and count of a hunk.
+// An empty range starts at the line before it.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
+
+func writeLines(out *bytes.Buffer, prefix byte, lines [][]byte) {
+	for _, line := range lines {
+		out.WriteByte(prefix)
+		out.Write(line)
+		if !bytes.HasSuffix(line, []byte{'\n'}) {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,73 @@
+package diff_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aswinkarthik/replace-text/diff"
+	"github.com/aswinkarthik/replace-text/replacer"
+	"github.com/stretchr/testify/assert"
+)
+
+func unified(t *testing.T, replacements map[string]string, input string, context int) string {
+	r, err := replacer.NewReplacer(replacements)
+	assert.NoError(t, err)
+
+	edits, err := r.Find("", strings.NewReader(input))
+	assert.NoError(t, err)
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, diff.Unified(out, "a/dir/file.txt", "b/dir/file.txt", []byte(input), edits, context))
+	return out.String()
+}
+
+func TestUnified(t *testing.T) {
+	input := "1\n2\n3 key\n4\n5\n6\n7\n8\n9\n10 key\n11\n"
+
+	t.Run("should write hunks with context lines", func(t *testing.T) {
+		expected := "--- a/dir/file.txt\n+++ b/dir/file.txt\n" +
+			"@@ -2,3 +2,3 @@\n 2\n-3 key\n+3 value\n 4\n" +
+			"@@ -9,3 +9,3 @@\n 9\n-10 key\n+10 value\n 11\n"
+		assert.Equal(t, expected, unified(t, map[string]string{"key": "value"}, input, 1))
+	})
+
+	t.Run("should merge hunks with overlapping context", func(t *testing.T) {
+		expected := "--- a/dir/file.txt\n+++ b/dir/file.txt\n" +
+			"@@ -1,11 +1,11 @@\n 1\n 2\n-3 key\n+3 value\n 4\n 5\n 6\n 7\n 8\n 9\n-10 key\n+10 value\n 11\n"
+		assert.Equal(t, expected, unified(t, map[string]string{"key": "value"}, input, 3))
+	})
+
+	t.Run("should number lines of later hunks after added lines", func(t *testing.T) {
+		expected := "--- a/dir/file.txt\n+++ b/dir/file.txt\n" +
+			"@@ -3 +3,2 @@\n-3 key\n+3 a\n+b\n" +
+			"@@ -10 +11,2 @@\n-10 key\n+10 a\n+b\n"
+		assert.Equal(t, expected, unified(t, map[string]string{"key": "a\nb"}, input, 0))
+	})
+
+	t.Run("should replace all lines touched by a match spanning lines", func(t *testing.T) {
+		expected := "--- a/dir/file.txt\n+++ b/dir/file.txt\n" +
+			"@@ -1,2 +1 @@\n-a\n-b\n+a\n"
+		assert.Equal(t, expected, unified(t, map[string]string{"\nb": ""}, "a\nb\nc\n", 0))
+	})
+
+	t.Run("should mark lines without a newline at the end of file", func(t *testing.T) {
+		expected := "--- a/dir/file.txt\n+++ b/dir/file.txt\n" +
+			"@@ -1,2 +1,2 @@\n a\n-key\n\\ No newline at end of file\n+value\n\\ No newline at end of file\n"
+		assert.Equal(t, expected, unified(t, map[string]string{"key": "value"}, "a\nkey", 3))
+	})
+
+	t.Run("should write nothing if there are no changes", func(t *testing.T) {
+		assert.Equal(t, "", unified(t, map[string]string{"key": "key"}, "a key", 3))
+		assert.Equal(t, "", unified(t, map[string]string{"key": "value"}, "a", 3))
+	})
+}
+
+func TestHunks(t *testing.T) {
+	t.Run("should write hunks without the header", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		err := diff.Hunks(out, []byte("a\nkey\n"), []replacer.Edit{{Start: 2, End: 5, Replacement: "value"}}, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "@@ -1,2 +1,2 @@\n a\n-key\n+value\n", out.String())
+	})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aswinkarthik/replace-text/diff"
+	"github.com/aswinkarthik/replace-text/fs"
+	"github.com/aswinkarthik/replace-text/replacer"
+)
+
+// dryRun prints the changes replacing would make in a file, instead of making them.
+// It prints a unified diff with the given number of context lines if showDiff is set,
+// or else only the path of a file that would change.
+func dryRun(showDiff bool, context int) func(fs fs.Fs, r *replacer.Replacer, path string) error {
+	return func(fs fs.Fs, r *replacer.Replacer, path string) error {
+		file, err := openInput(fs, path)
+		if err != nil {
+			return fmt.Errorf("error opening input file: %v", err)
+		}
+		defer file.Close()
+
+		// The original content is needed to print the lines around changes
+		original, err := ioutil.ReadAll(file)
+		if err != nil {
+			return fmt.Errorf("error reading input file %s: %v", path, err)
+		}
+
+		edits, err := r.Find(path, bytes.NewReader(original))
+		if err != nil {
+			return fmt.Errorf("error finding matches in input file: %v", err)
+		}
+
+		if !showDiff {
+			if len(edits) > 0 {
+				_, err = fmt.Fprintln(stdout, path)
+			}
+			return err
+		}
+
+		from, to := diffNames(path)
+		return diff.Unified(stdout, from, to, original, edits, context)
+	}
+}
+
+// diffNames returns the names of the original and the changed file at path
+// in the header of its diff. Paths in the working directory are prefixed with
+// a/ and b/ to be applied with patch -p1 or git apply, while other paths are
+// relative to the working directory when possible, and are kept as they are.
+func diffNames(path string) (string, string) {
+	if path == stdinPath {
+		return stdinLabel, stdinLabel
+	}
+
+	name := filepath.Clean(path)
+	if filepath.IsAbs(name) {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, name); err == nil {
+				name = rel
+			}
+		}
+	}
+
+	name = filepath.ToSlash(name)
+	if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return name, name
+	}
+
+	return "a/" + name, "b/" + name
+}
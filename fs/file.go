@@ -15,4 +15,10 @@ type ReadOnlyFile interface {
 type WritableFile interface {
 	io.Writer
 	io.Closer
+
+	// Name returns the path of the file
+	Name() string
+
+	// Sync commits the written contents to stable storage
+	Sync() error
 }
@@ -3,9 +3,15 @@ package fs
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 )
 
+// PermissionBits are the bits of a file mode that files are created with:
+// the permissions along with the setuid, setgid and sticky bits
+const PermissionBits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
 // Fs is an abstraction to access the filesystem
 type Fs interface {
 	// IsFile returns true if the path is a file
@@ -29,11 +35,34 @@ type Fs interface {
 	// It will error out if file already exists.
 	Create(path string, mode os.FileMode) (WritableFile, error)
 
+	// CreateTemp will create a new file for writing in the directory dir
+	// with the specified file mode. The name of the file is generated by
+	// replacing the last "*" in pattern with a random string.
+	CreateTemp(dir, pattern string, mode os.FileMode) (WritableFile, error)
+
+	// Rename moves oldpath to newpath, replacing newpath if it is a file.
+	Rename(oldpath, newpath string) error
+
+	// Remove deletes the file at path.
+	Remove(path string) error
+
 	// Exists returns true if is a valid file or directory.
 	Exists(path string) (bool, error)
 
 	// FileMode will return the file mode of the given path.
 	FileMode(path string) (os.FileMode, error)
+
+	// MkdirAll creates the directory at path along with any missing parents.
+	// It does nothing if the directory already exists.
+	MkdirAll(path string, mode os.FileMode) error
+
+	// ReadDir lists the entries of the directory at path sorted by name.
+	// Symbolic links are described as links and not followed.
+	ReadDir(path string) ([]os.FileInfo, error)
+
+	// EvalSymlinks returns the path after following any symbolic links in it.
+	// It returns an error if the path does not exist.
+	EvalSymlinks(path string) (string, error)
 }
 
 var _ Fs = (*osFs)(nil)
@@ -91,13 +120,37 @@ func (f *osFs) Create(path string, mode os.FileMode) (WritableFile, error) {
 		return nil, fmt.Errorf("cannot create file %s as it already exists", path)
 	}
 
-	return os.OpenFile(path, os.O_CREATE, mode)
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_TRUNC, mode)
+}
+
+func (f *osFs) CreateTemp(dir, pattern string, mode os.FileMode) (WritableFile, error) {
+	file, err := ioutil.TempFile(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	// Temp files are created with 0600, which is not affected by umask
+	if err := file.Chmod(mode); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (f *osFs) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func (f *osFs) Remove(path string) error {
+	return os.Remove(path)
 }
 
 func (f *osFs) Exists(path string) (bool, error) {
 	_, err := os.Stat(path)
 	if err != nil {
-		if err == os.ErrNotExist {
+		if os.IsNotExist(err) {
 			return false, nil
 		}
 
@@ -107,6 +160,18 @@ func (f *osFs) Exists(path string) (bool, error) {
 	return true, nil
 }
 
+func (f *osFs) MkdirAll(path string, mode os.FileMode) error {
+	return os.MkdirAll(path, mode)
+}
+
+func (f *osFs) ReadDir(path string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(path)
+}
+
+func (f *osFs) EvalSymlinks(path string) (string, error) {
+	return filepath.EvalSymlinks(path)
+}
+
 func (f *osFs) FileMode(path string) (os.FileMode, error) {
 	info, err := os.Stat(path)
 	if err != nil {
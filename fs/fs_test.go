@@ -0,0 +1,91 @@
+package fs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aswinkarthik/replace-text/fs"
+	"github.com/stretchr/testify/assert"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "replace-text")
+	assert.NoError(t, err)
+
+	return dir
+}
+
+func TestOsFs_Create(t *testing.T) {
+	t.Run("should create a writable file with the given mode", func(t *testing.T) {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "file.txt")
+		osFs := fs.NewOsFs()
+
+		file, err := osFs.Create(path, 0640)
+		assert.NoError(t, err)
+		_, err = file.Write([]byte("content"))
+		assert.NoError(t, err)
+		assert.NoError(t, file.Close())
+
+		data, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "content", string(data))
+
+		mode, err := osFs.FileMode(path)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0640), mode.Perm()&0750)
+	})
+
+	t.Run("should return error if the file exists", func(t *testing.T) {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "file.txt")
+		assert.NoError(t, ioutil.WriteFile(path, []byte("content"), 0600))
+
+		_, err := fs.NewOsFs().Create(path, 0600)
+		assert.EqualError(t, err, "cannot create file "+path+" as it already exists")
+	})
+}
+
+func TestOsFs_Exists(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	osFs := fs.NewOsFs()
+
+	exists, err := osFs.Exists(dir)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = osFs.Exists(filepath.Join(dir, "missing"))
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestOsFs_CreateTemp(t *testing.T) {
+	t.Run("should create a temp file with the given mode that can be renamed", func(t *testing.T) {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		target := filepath.Join(dir, "file.txt")
+		osFs := fs.NewOsFs()
+
+		file, err := osFs.CreateTemp(dir, ".file.txt.*", 0751)
+		assert.NoError(t, err)
+		assert.Equal(t, dir, filepath.Dir(file.Name()))
+
+		_, err = file.Write([]byte("content"))
+		assert.NoError(t, err)
+		assert.NoError(t, file.Sync())
+		assert.NoError(t, file.Close())
+		assert.NoError(t, osFs.Rename(file.Name(), target))
+
+		mode, err := osFs.FileMode(target)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0751), mode.Perm())
+
+		assert.NoError(t, osFs.Remove(target))
+		assert.False(t, osFs.IsFile(target))
+	})
+}
@@ -0,0 +1,238 @@
+package fs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var _ Fs = (*memFs)(nil)
+
+// memFs is an in-memory Fs. Paths are cleaned before use,
+// and the root and current directories always exist.
+type memFs struct {
+	entries map[string]*memEntry
+	// tempCount generates names of temporary files
+	tempCount int
+}
+
+type memEntry struct {
+	mode os.FileMode
+	data []byte
+}
+
+// NewMemFs can be used to create an in-memory FS.
+// It does not touch the actual filesystem, and is meant for tests.
+func NewMemFs() Fs {
+	return &memFs{
+		entries: map[string]*memEntry{
+			".": {mode: os.ModeDir | 0755},
+			"/": {mode: os.ModeDir | 0755},
+		},
+	}
+}
+
+func (f *memFs) IsFile(path string) bool {
+	entry, ok := f.entries[filepath.Clean(path)]
+	return ok && !entry.mode.IsDir()
+}
+
+func (f *memFs) IsDir(path string) bool {
+	entry, ok := f.entries[filepath.Clean(path)]
+	return ok && entry.mode.IsDir()
+}
+
+func (f *memFs) DevNull() io.Writer {
+	return ioutil.Discard
+}
+
+func (f *memFs) Open(path string) (ReadOnlyFile, error) {
+	entry, ok := f.entries[filepath.Clean(path)]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+
+	if entry.mode.IsDir() {
+		return nil, &os.PathError{Op: "open", Path: path, Err: fmt.Errorf("is a directory")}
+	}
+
+	return &memReadOnlyFile{bytes.NewReader(entry.data)}, nil
+}
+
+func (f *memFs) Create(path string, mode os.FileMode) (WritableFile, error) {
+	path = filepath.Clean(path)
+	if _, exists := f.entries[path]; exists {
+		return nil, fmt.Errorf("cannot create file %s as it already exists", path)
+	}
+
+	if !f.IsDir(filepath.Dir(path)) {
+		return nil, &os.PathError{Op: "create", Path: path, Err: os.ErrNotExist}
+	}
+
+	entry := &memEntry{mode: mode & PermissionBits}
+	f.entries[path] = entry
+	return &memWritableFile{fs: f, name: path, entry: entry}, nil
+}
+
+func (f *memFs) CreateTemp(dir, pattern string, mode os.FileMode) (WritableFile, error) {
+	f.tempCount++
+	name := strconv.Itoa(f.tempCount)
+	if i := strings.LastIndex(pattern, "*"); i >= 0 {
+		name = pattern[:i] + name + pattern[i+1:]
+	} else {
+		name = pattern + name
+	}
+
+	return f.Create(filepath.Join(dir, name), mode)
+}
+
+func (f *memFs) Rename(oldpath, newpath string) error {
+	oldpath, newpath = filepath.Clean(oldpath), filepath.Clean(newpath)
+	entry, ok := f.entries[oldpath]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrNotExist}
+	}
+
+	if f.IsDir(newpath) || !f.IsDir(filepath.Dir(newpath)) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrInvalid}
+	}
+
+	delete(f.entries, oldpath)
+	f.entries[newpath] = entry
+	return nil
+}
+
+func (f *memFs) Remove(path string) error {
+	path = filepath.Clean(path)
+	if _, ok := f.entries[path]; !ok {
+		return &os.PathError{Op: "remove", Path: path, Err: os.ErrNotExist}
+	}
+
+	delete(f.entries, path)
+	return nil
+}
+
+func (f *memFs) Exists(path string) (bool, error) {
+	_, ok := f.entries[filepath.Clean(path)]
+	return ok, nil
+}
+
+// EvalSymlinks returns the cleaned path, as there are no symbolic links in memory
+func (f *memFs) EvalSymlinks(path string) (string, error) {
+	path = filepath.Clean(path)
+	if _, ok := f.entries[path]; !ok {
+		return "", &os.PathError{Op: "lstat", Path: path, Err: os.ErrNotExist}
+	}
+
+	return path, nil
+}
+
+func (f *memFs) FileMode(path string) (os.FileMode, error) {
+	entry, ok := f.entries[filepath.Clean(path)]
+	if !ok {
+		return 0, &os.PathError{Op: "stat", Path: path, Err: os.ErrNotExist}
+	}
+
+	return entry.mode, nil
+}
+
+func (f *memFs) MkdirAll(path string, mode os.FileMode) error {
+	path = filepath.Clean(path)
+	if f.IsDir(path) {
+		return nil
+	}
+
+	if f.IsFile(path) {
+		return &os.PathError{Op: "mkdir", Path: path, Err: os.ErrExist}
+	}
+
+	if err := f.MkdirAll(filepath.Dir(path), mode); err != nil {
+		return err
+	}
+
+	f.entries[path] = &memEntry{mode: os.ModeDir | mode.Perm()}
+	return nil
+}
+
+func (f *memFs) ReadDir(path string) ([]os.FileInfo, error) {
+	path = filepath.Clean(path)
+	if !f.IsDir(path) {
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: os.ErrNotExist}
+	}
+
+	var infos []os.FileInfo
+	for name, entry := range f.entries {
+		if name == path || filepath.Dir(name) != path {
+			continue
+		}
+
+		infos = append(infos, &memFileInfo{name: filepath.Base(name), entry: entry})
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+
+	return infos, nil
+}
+
+// memFileInfo describes an entry of memFs
+type memFileInfo struct {
+	name  string
+	entry *memEntry
+}
+
+func (i *memFileInfo) Name() string       { return i.name }
+func (i *memFileInfo) Size() int64        { return int64(len(i.entry.data)) }
+func (i *memFileInfo) Mode() os.FileMode  { return i.entry.mode }
+func (i *memFileInfo) ModTime() time.Time { return time.Time{} }
+func (i *memFileInfo) IsDir() bool        { return i.entry.mode.IsDir() }
+func (i *memFileInfo) Sys() interface{}   { return nil }
+
+type memReadOnlyFile struct {
+	*bytes.Reader
+}
+
+func (f *memReadOnlyFile) Close() error {
+	return nil
+}
+
+type memWritableFile struct {
+	fs     *memFs
+	name   string
+	entry  *memEntry
+	closed bool
+}
+
+func (f *memWritableFile) Write(p []byte) (int, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+
+	f.entry.data = append(f.entry.data, p...)
+	return len(p), nil
+}
+
+func (f *memWritableFile) Close() error {
+	if f.closed {
+		return os.ErrClosed
+	}
+
+	f.closed = true
+	return nil
+}
+
+func (f *memWritableFile) Name() string {
+	return f.name
+}
+
+func (f *memWritableFile) Sync() error {
+	return nil
+}
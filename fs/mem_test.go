@@ -0,0 +1,99 @@
+package fs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/aswinkarthik/replace-text/fs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemFs(t *testing.T) {
+	t.Run("should create files only in existing directories", func(t *testing.T) {
+		memFs := fs.NewMemFs()
+
+		_, err := memFs.Create("dir/file.txt", 0644)
+		assert.Error(t, err)
+
+		assert.NoError(t, memFs.MkdirAll("dir/nested", 0755))
+		assert.True(t, memFs.IsDir("dir"))
+		assert.True(t, memFs.IsDir("dir/nested"))
+
+		file, err := memFs.Create("dir/file.txt", 0644)
+		assert.NoError(t, err)
+		_, err = file.Write([]byte("content"))
+		assert.NoError(t, err)
+		assert.NoError(t, file.Close())
+
+		assert.True(t, memFs.IsFile("./dir/file.txt"))
+		_, err = memFs.Create("dir/file.txt", 0644)
+		assert.EqualError(t, err, "cannot create file dir/file.txt as it already exists")
+	})
+
+	t.Run("should read written files", func(t *testing.T) {
+		memFs := fs.NewMemFs()
+		file, err := memFs.Create("file.txt", 0600)
+		assert.NoError(t, err)
+		_, err = file.Write([]byte("content"))
+		assert.NoError(t, err)
+		assert.NoError(t, file.Close())
+
+		readFile, err := memFs.Open("file.txt")
+		assert.NoError(t, err)
+		data, err := ioutil.ReadAll(readFile)
+		assert.NoError(t, err)
+		assert.Equal(t, "content", string(data))
+
+		mode, err := memFs.FileMode("file.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), mode)
+	})
+
+	t.Run("should rename temp files over existing files", func(t *testing.T) {
+		memFs := fs.NewMemFs()
+		original, err := memFs.Create("file.txt", 0600)
+		assert.NoError(t, err)
+		assert.NoError(t, original.Close())
+
+		temp, err := memFs.CreateTemp(".", ".file.txt.*", 0700)
+		assert.NoError(t, err)
+		assert.Equal(t, ".file.txt.1", temp.Name())
+		assert.NoError(t, temp.Close())
+
+		assert.NoError(t, memFs.Rename(temp.Name(), "file.txt"))
+		assert.False(t, memFs.IsFile(temp.Name()))
+
+		mode, err := memFs.FileMode("file.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0700), mode)
+
+		assert.NoError(t, memFs.Remove("file.txt"))
+		exists, err := memFs.Exists("file.txt")
+		assert.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("should list directory entries sorted by name", func(t *testing.T) {
+		memFs := fs.NewMemFs()
+		assert.NoError(t, memFs.MkdirAll("src/b", 0755))
+		file, err := memFs.Create("src/a.txt", 0644)
+		assert.NoError(t, err)
+		_, err = file.Write([]byte("abc"))
+		assert.NoError(t, err)
+		assert.NoError(t, file.Close())
+		assert.NoError(t, memFs.MkdirAll("src/b/c", 0755))
+
+		infos, err := memFs.ReadDir("src")
+		assert.NoError(t, err)
+		assert.Len(t, infos, 2)
+		assert.Equal(t, "a.txt", infos[0].Name())
+		assert.Equal(t, int64(3), infos[0].Size())
+		assert.False(t, infos[0].IsDir())
+		assert.Equal(t, "b", infos[1].Name())
+		assert.True(t, infos[1].IsDir())
+
+		_, err = memFs.ReadDir("src/a.txt")
+		assert.Error(t, err)
+	})
+}
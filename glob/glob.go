@@ -0,0 +1,188 @@
+// Package glob matches slash separated paths against shell style patterns.
+//
+// A pattern supports
+//
+//   - any sequence of characters other than /
+//     ?       any single character other than /
+//     [a-z]   any character in the class, [!a-z] or [^a-z] negates it
+//     **      any number of path segments, when it is a whole segment
+//     \c      the character c literally
+//
+// A pattern without a / is matched against the last segment of a path,
+// while a pattern with a / is matched against the whole path.
+// A leading / only anchors the pattern, and is not part of it.
+package glob
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Glob is a compiled pattern
+type Glob struct {
+	pattern  string
+	re       *regexp.Regexp
+	basename bool
+}
+
+// Compile parses the pattern into a Glob
+func Compile(pattern string) (*Glob, error) {
+	g := &Glob{pattern: pattern}
+
+	trimmed := strings.TrimSuffix(pattern, "/")
+	if strings.HasPrefix(trimmed, "/") {
+		trimmed = strings.TrimPrefix(trimmed, "/")
+	} else if !strings.Contains(trimmed, "/") {
+		g.basename = true
+	}
+
+	expr, err := translate(trimmed)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing glob %q: %v", pattern, err)
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing glob %q: %v", pattern, err)
+	}
+	g.re = re
+
+	return g, nil
+}
+
+// MustCompile is like Compile but panics if the pattern cannot be parsed
+func MustCompile(pattern string) *Glob {
+	g, err := Compile(pattern)
+	if err != nil {
+		panic(err)
+	}
+
+	return g
+}
+
+// String returns the source pattern
+func (g *Glob) String() string {
+	return g.pattern
+}
+
+// Match returns true if the slash separated path matches the pattern
+func (g *Glob) Match(path string) bool {
+	if g.basename {
+		path = path[strings.LastIndex(path, "/")+1:]
+	}
+
+	return g.re.MatchString(path)
+}
+
+// translate converts the glob to an anchored regular expression
+func translate(pattern string) (string, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		last := i == len(segments)-1
+
+		if segment == "**" {
+			if last {
+				expr.WriteString(".*")
+			} else {
+				expr.WriteString("(?:.*/)?")
+			}
+			continue
+		}
+
+		if err := translateSegment(&expr, segment); err != nil {
+			return "", err
+		}
+		if !last {
+			expr.WriteString("/")
+		}
+	}
+
+	expr.WriteString("$")
+	return expr.String(), nil
+}
+
+func translateSegment(expr *strings.Builder, segment string) error {
+	for i := 0; i < len(segment); i++ {
+		switch ch := segment[i]; ch {
+		case '*':
+			expr.WriteString("[^/]*")
+		case '?':
+			expr.WriteString("[^/]")
+		case '\\':
+			if i+1 == len(segment) {
+				return fmt.Errorf("trailing escape character")
+			}
+			i++
+			expr.WriteString(regexp.QuoteMeta(segment[i : i+1]))
+		case '[':
+			end := classEnd(segment, i)
+			if end < 0 {
+				return fmt.Errorf("unterminated character class")
+			}
+			expr.WriteString(translateClass(segment[i+1 : end]))
+			i = end
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+
+	return nil
+}
+
+// classEnd returns the index of the ] closing the class starting at start,
+// or -1 if the class is not closed.
+func classEnd(segment string, start int) int {
+	i := start + 1
+	if i < len(segment) && (segment[i] == '!' || segment[i] == '^') {
+		i++
+	}
+	// A ] right after the opening bracket is part of the class
+	if i < len(segment) && segment[i] == ']' {
+		i++
+	}
+
+	for ; i < len(segment); i++ {
+		switch segment[i] {
+		case '\\':
+			i++
+		case ']':
+			return i
+		}
+	}
+
+	return -1
+}
+
+func translateClass(class string) string {
+	var expr strings.Builder
+	expr.WriteString("[")
+	if len(class) > 0 && (class[0] == '!' || class[0] == '^') {
+		expr.WriteString("^/")
+		class = class[1:]
+	}
+
+	for i := 0; i < len(class); i++ {
+		switch ch := class[i]; ch {
+		case '\\':
+			if i+1 < len(class) {
+				i++
+				expr.WriteString(`\`)
+				expr.WriteByte(class[i])
+			}
+		case '-':
+			expr.WriteByte(ch)
+		case '[', ']', '^':
+			expr.WriteString(`\`)
+			expr.WriteByte(ch)
+		default:
+			expr.WriteByte(ch)
+		}
+	}
+
+	expr.WriteString("]")
+	return expr.String()
+}
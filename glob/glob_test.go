@@ -0,0 +1,52 @@
+package glob_test
+
+import (
+	"testing"
+
+	"github.com/aswinkarthik/replace-text/glob"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlob_Match(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "replacer/node.go", true},
+		{"*.go", "main.go.orig", false},
+		{"?.txt", "a.txt", true},
+		{"?.txt", "ab.txt", false},
+		{"[a-c].txt", "b.txt", true},
+		{"[!a-c].txt", "b.txt", false},
+		{"[!a-c].txt", "d.txt", true},
+		{"[]].txt", "].txt", true},
+		{`\*.txt`, "*.txt", true},
+		{`\*.txt`, "a.txt", false},
+		{"vendor/**", "vendor/github.com/a.go", true},
+		{"vendor/**", "src/vendor/a.go", false},
+		{"vendor/**", "vendor", false},
+		{"**/vendor/**", "src/vendor/a.go", true},
+		{"**/vendor/**", "vendor/a.go", true},
+		{"src/**/*.go", "src/a.go", true},
+		{"src/**/*.go", "src/b/c/a.go", true},
+		{"src/*.go", "src/b/a.go", false},
+		{"/main.go", "main.go", true},
+		{"/main.go", "cmd/main.go", false},
+		{"docs/", "docs", true},
+	}
+
+	for _, test := range tests {
+		g, err := glob.Compile(test.pattern)
+		assert.NoError(t, err)
+		assert.Equal(t, test.match, g.Match(test.path), "%s on %s", test.pattern, test.path)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	for _, pattern := range []string{"[a-", `a\`} {
+		_, err := glob.Compile(pattern)
+		assert.Error(t, err, pattern)
+	}
+}
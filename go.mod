@@ -3,6 +3,8 @@ module github.com/aswinkarthik/replace-text
 go 1.13
 
 require (
+	github.com/BurntSushi/toml v0.4.1
 	github.com/stretchr/testify v1.5.1
 	github.com/urfave/cli/v2 v2.2.0
+	gopkg.in/yaml.v2 v2.2.2
 )
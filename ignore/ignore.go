@@ -0,0 +1,140 @@
+// Package ignore matches paths against the rules of gitignore style files
+package ignore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aswinkarthik/replace-text/glob"
+)
+
+// FileNames are the ignore files read in every directory,
+// in the increasing order of their precedence
+var FileNames = []string{".gitignore", ".ignore", ".replace-text-ignore"}
+
+// GitExclude is the path of the ignore file of a git repository,
+// relative to its root. It has a lower precedence than FileNames.
+const GitExclude = ".git/info/exclude"
+
+type rule struct {
+	glob    *glob.Glob
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher holds the rules of a single ignore file
+type Matcher struct {
+	dir   string
+	rules []rule
+}
+
+// Parse reads the rules of an ignore file. dir is the slash separated path
+// of the directory the rules apply to, relative to the root of the paths
+// that are matched later. It is empty for the root itself.
+func Parse(reader io.Reader, dir string) (*Matcher, error) {
+	m := &Matcher{dir: strings.Trim(dir, "/")}
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		r, ok := parseRule(scanner.Text())
+		if ok {
+			m.rules = append(m.rules, r)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading ignore rules: %v", err)
+	}
+
+	return m, nil
+}
+
+// parseRule parses a line of an ignore file.
+// Returns false for blank lines, comments and invalid patterns,
+// which are skipped like git does.
+func parseRule(line string) (rule, bool) {
+	line = trimTrailingSpaces(strings.TrimSuffix(line, "\r"))
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false
+	}
+
+	var r rule
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	if line == "" {
+		return rule{}, false
+	}
+
+	g, err := glob.Compile(line)
+	if err != nil {
+		return rule{}, false
+	}
+	r.glob = g
+
+	return r, true
+}
+
+// trimTrailingSpaces removes spaces at the end of the line,
+// unless they are escaped with a backslash
+func trimTrailingSpaces(line string) string {
+	end := len(line)
+	for end > 0 && line[end-1] == ' ' {
+		if end > 1 && line[end-2] == '\\' {
+			break
+		}
+		end--
+	}
+
+	return line[:end]
+}
+
+// Match reports whether any rule matches the slash separated path, and if so,
+// whether the path is ignored. The last matching rule decides, so that
+// a negated rule can include a path excluded by an earlier rule.
+func (m *Matcher) Match(path string, isDir bool) (matched, ignored bool) {
+	if m.dir != "" {
+		if !strings.HasPrefix(path, m.dir+"/") {
+			return false, false
+		}
+		path = path[len(m.dir)+1:]
+	}
+
+	for i := len(m.rules) - 1; i >= 0; i-- {
+		r := m.rules[i]
+		if r.dirOnly && !isDir {
+			continue
+		}
+
+		if r.glob.Match(path) {
+			return true, !r.negate
+		}
+	}
+
+	return false, false
+}
+
+// Stack holds the matchers of nested directories,
+// where later matchers take precedence over earlier ones
+type Stack []*Matcher
+
+// Ignored returns true if the path is ignored by the matcher
+// with the highest precedence that matches it
+func (s Stack) Ignored(path string, isDir bool) bool {
+	for i := len(s) - 1; i >= 0; i-- {
+		if matched, ignored := s[i].Match(path, isDir); matched {
+			return ignored
+		}
+	}
+
+	return false
+}
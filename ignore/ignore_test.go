@@ -0,0 +1,88 @@
+package ignore_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aswinkarthik/replace-text/ignore"
+	"github.com/stretchr/testify/assert"
+)
+
+func parse(t *testing.T, rules, dir string) *ignore.Matcher {
+	m, err := ignore.Parse(strings.NewReader(rules), dir)
+	assert.NoError(t, err)
+	return m
+}
+
+func TestMatcher_Match(t *testing.T) {
+	t.Run("should match rules without a slash at any depth", func(t *testing.T) {
+		m := parse(t, "# comment\n\n*.log\n", "")
+
+		matched, ignored := m.Match("app.log", false)
+		assert.True(t, matched)
+		assert.True(t, ignored)
+
+		matched, ignored = m.Match("logs/app.log", false)
+		assert.True(t, matched)
+		assert.True(t, ignored)
+
+		matched, _ = m.Match("app.txt", false)
+		assert.False(t, matched)
+	})
+
+	t.Run("should anchor rules with a slash to the directory of the file", func(t *testing.T) {
+		m := parse(t, "/build\ndocs/*.md\n", "src")
+
+		_, ignored := m.Match("src/build", true)
+		assert.True(t, ignored)
+		_, ignored = m.Match("src/lib/build", true)
+		assert.False(t, ignored)
+		_, ignored = m.Match("src/docs/a.md", false)
+		assert.True(t, ignored)
+		_, ignored = m.Match("build", true)
+		assert.False(t, ignored)
+	})
+
+	t.Run("should match rules ending with a slash only on directories", func(t *testing.T) {
+		m := parse(t, "tmp/\n", "")
+
+		_, ignored := m.Match("a/tmp", true)
+		assert.True(t, ignored)
+		_, ignored = m.Match("a/tmp", false)
+		assert.False(t, ignored)
+	})
+
+	t.Run("should let the last matching rule decide", func(t *testing.T) {
+		m := parse(t, "*.log\n!keep.log\n", "")
+
+		matched, ignored := m.Match("keep.log", false)
+		assert.True(t, matched)
+		assert.False(t, ignored)
+		_, ignored = m.Match("drop.log", false)
+		assert.True(t, ignored)
+	})
+
+	t.Run("should handle escaped characters and trailing spaces", func(t *testing.T) {
+		m := parse(t, "\\#notes  \n\\!bang\nspace\\ \n", "")
+
+		_, ignored := m.Match("#notes", false)
+		assert.True(t, ignored)
+		_, ignored = m.Match("!bang", false)
+		assert.True(t, ignored)
+		_, ignored = m.Match("space ", false)
+		assert.True(t, ignored)
+	})
+}
+
+func TestStack_Ignored(t *testing.T) {
+	t.Run("should prefer matchers of nested directories", func(t *testing.T) {
+		stack := ignore.Stack{
+			parse(t, "*.gen.go\n", ""),
+			parse(t, "!*.gen.go\n", "api"),
+		}
+
+		assert.True(t, stack.Ignored("cmd/a.gen.go", false))
+		assert.False(t, stack.Ignored("api/a.gen.go", false))
+		assert.False(t, stack.Ignored("api/a.go", false))
+	})
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aswinkarthik/replace-text/fs"
+	"github.com/aswinkarthik/replace-text/replacer"
+)
+
+const (
+	// defaultBackupSuffix is used when --backup is given without a suffix
+	defaultBackupSuffix = ".orig"
+	// backupTimestamp in a backup suffix is replaced with the time of the run
+	backupTimestamp       = "{timestamp}"
+	backupTimestampLayout = "20060102150405"
+
+	// preservedMode are the bits of the mode of a file kept when it is rewritten
+	preservedMode = fs.PermissionBits
+)
+
+// backup decides where the original of a file edited in place is kept.
+// No backups are kept if both suffix and dir are empty.
+type backup struct {
+	suffix string
+	// dir mirrors the paths of files edited, so that backups
+	// do not clutter the working copy
+	dir  string
+	time time.Time
+}
+
+func (b backup) enabled() bool {
+	return b.suffix != "" || b.dir != ""
+}
+
+// path returns the path of the backup for the file at path
+func (b backup) path(path string) string {
+	suffix := strings.Replace(b.suffix, backupTimestamp, b.time.Format(backupTimestampLayout), -1)
+	if b.dir == "" {
+		return path + suffix
+	}
+
+	return mirrorPath(b.dir, path) + suffix
+}
+
+// mirrorPath returns the path of path under dir. Paths outside of
+// the working directory are mirrored by their absolute path.
+func mirrorPath(dir, path string) string {
+	mirrored := filepath.Clean(path)
+	if filepath.IsAbs(mirrored) || mirrored == ".." || strings.HasPrefix(mirrored, ".."+string(filepath.Separator)) {
+		if abs, err := filepath.Abs(mirrored); err == nil {
+			mirrored = strings.TrimPrefix(abs, filepath.VolumeName(abs))
+		}
+	}
+
+	return filepath.Join(dir, mirrored)
+}
+
+// save copies the original to its backup path with the same file mode.
+// An existing backup is replaced, writing to the target of a symbolic link.
+func (b backup) save(fs fs.Fs, original io.Reader, path string, mode os.FileMode) error {
+	backupPath := b.path(path)
+	backupDir := filepath.Dir(backupPath)
+	if err := fs.MkdirAll(backupDir, 0755); err != nil {
+		return fmt.Errorf("error creating backup directory: %v", err)
+	}
+	if resolved, err := fs.EvalSymlinks(backupPath); err == nil {
+		backupPath, backupDir = resolved, filepath.Dir(resolved)
+	}
+
+	temp, err := fs.CreateTemp(backupDir, "."+filepath.Base(backupPath)+".*", mode)
+	if err != nil {
+		return fmt.Errorf("error creating backup of %s: %v", path, err)
+	}
+
+	_, err = io.Copy(temp, original)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = fs.Rename(temp.Name(), backupPath)
+	}
+	if err != nil {
+		_ = fs.Remove(temp.Name())
+		return fmt.Errorf("error creating backup of %s: %v", path, err)
+	}
+
+	return nil
+}
+
+// replaceInPlace returns a function that replaces the content of the file at path.
+// The result is written to a temporary file in the same directory, which is
+// renamed over the original, so that the file is never left half written.
+// The file mode of the original is preserved, and files without matches are left untouched.
+// The original is copied to its backup before the rename.
+func replaceInPlace(b backup) func(fs fs.Fs, r *replacer.Replacer, path string) error {
+	return func(fs fs.Fs, r *replacer.Replacer, path string) error {
+		return rewriteInPlace(fs, b, path, func(reader io.Reader, writer io.Writer) error {
+			return r.ReplaceFile(path, reader, writer)
+		})
+	}
+}
+
+// rewriteInPlace replaces the file at path with the content written by rewrite.
+// The content is written to a temporary file that is renamed over the file,
+// so that the file is never left partially written. Symbolic links are kept,
+// replacing the file they point to instead.
+// The file is left untouched if rewrite returns replacer.ErrNoMatchesFound.
+func rewriteInPlace(fs fs.Fs, b backup, path string, rewrite func(reader io.Reader, writer io.Writer) error) error {
+	target, err := fs.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("error resolving %s: %v", path, err)
+	}
+
+	mode, err := fs.FileMode(target)
+	if err != nil {
+		return fmt.Errorf("error reading file mode of %s: %v", path, err)
+	}
+
+	file, err := fs.Open(target)
+	if err != nil {
+		return fmt.Errorf("error opening input file: %v", err)
+	}
+	defer file.Close()
+
+	temp, err := fs.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".*", mode&preservedMode)
+	if err != nil {
+		return fmt.Errorf("error creating temporary file for %s: %v", path, err)
+	}
+
+	replaceErr := rewrite(file, temp)
+	if replaceErr == nil {
+		replaceErr = temp.Sync()
+	}
+	if err := temp.Close(); err != nil && replaceErr == nil {
+		replaceErr = err
+	}
+
+	if replaceErr != nil {
+		_ = fs.Remove(temp.Name())
+		if replaceErr == replacer.ErrNoMatchesFound {
+			return nil
+		}
+
+		return fmt.Errorf("error finding and replacing content in input file: %v", replaceErr)
+	}
+
+	if b.enabled() {
+		_, err := file.Seek(0, io.SeekStart)
+		if err == nil {
+			err = b.save(fs, file, path, mode&preservedMode)
+		}
+		if err != nil {
+			_ = fs.Remove(temp.Name())
+			return err
+		}
+	}
+
+	if err := fs.Rename(temp.Name(), target); err != nil {
+		_ = fs.Remove(temp.Name())
+		return fmt.Errorf("error replacing %s: %v", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aswinkarthik/replace-text/fs"
+	"github.com/aswinkarthik/replace-text/replacer"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, fs fs.Fs, path, content string, mode os.FileMode) {
+	file, err := fs.Create(path, mode)
+	assert.NoError(t, err)
+	_, err = file.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+}
+
+func readFile(t *testing.T, fs fs.Fs, path string) string {
+	file, err := fs.Open(path)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	assert.NoError(t, err)
+	return string(data)
+}
+
+func TestReplaceInPlace(t *testing.T) {
+	r, err := replacer.NewReplacer(map[string]string{"key1": "value1"})
+	assert.NoError(t, err)
+
+	t.Run("should preserve setuid, setgid and sticky bits", func(t *testing.T) {
+		memFs := fs.NewMemFs()
+		mode := 0755 | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+		writeFile(t, memFs, "file.txt", "key1", mode)
+
+		assert.NoError(t, replaceInPlace(backup{})(memFs, r, "file.txt"))
+
+		actual, err := memFs.FileMode("file.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, mode, actual)
+	})
+
+	t.Run("should replace the target of a symbolic link keeping the link", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "replace-text")
+		assert.NoError(t, err)
+		defer os.RemoveAll(dir)
+		target, link := filepath.Join(dir, "target.txt"), filepath.Join(dir, "link.txt")
+		assert.NoError(t, ioutil.WriteFile(target, []byte("key1"), 0640))
+		assert.NoError(t, os.Symlink("target.txt", link))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "backup.txt"), []byte("old"), 0600))
+		assert.NoError(t, os.Symlink("backup.txt", link+".orig"))
+
+		assert.NoError(t, replaceInPlace(backup{suffix: ".orig"})(fs.NewOsFs(), r, link))
+
+		info, err := os.Lstat(link)
+		assert.NoError(t, err)
+		assert.NotZero(t, info.Mode()&os.ModeSymlink)
+		info, err = os.Stat(target)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0640), info.Mode())
+
+		osFs := fs.NewOsFs()
+		assert.Equal(t, "value1", readFile(t, osFs, link))
+		assert.Equal(t, "value1", readFile(t, osFs, target))
+		info, err = os.Lstat(link + ".orig")
+		assert.NoError(t, err)
+		assert.NotZero(t, info.Mode()&os.ModeSymlink)
+		assert.Equal(t, "key1", readFile(t, osFs, filepath.Join(dir, "backup.txt")))
+	})
+
+	t.Run("should replace the file preserving its mode", func(t *testing.T) {
+		memFs := fs.NewMemFs()
+		writeFile(t, memFs, "file.txt", "key1 and key2", 0751)
+
+		assert.NoError(t, replaceInPlace(backup{})(memFs, r, "file.txt"))
+
+		assert.Equal(t, "value1 and key2", readFile(t, memFs, "file.txt"))
+		mode, err := memFs.FileMode("file.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0751), mode)
+		assert.False(t, memFs.IsFile(".file.txt.1"))
+	})
+
+	t.Run("should leave files without matches untouched", func(t *testing.T) {
+		memFs := fs.NewMemFs()
+		writeFile(t, memFs, "file.txt", "key2", 0644)
+
+		assert.NoError(t, replaceInPlace(backup{suffix: ".orig"})(memFs, r, "file.txt"))
+
+		assert.Equal(t, "key2", readFile(t, memFs, "file.txt"))
+		assert.False(t, memFs.IsFile("file.txt.orig"))
+		assert.False(t, memFs.IsFile(".file.txt.1"))
+	})
+
+	t.Run("should keep a backup with the suffix", func(t *testing.T) {
+		memFs := fs.NewMemFs()
+		assert.NoError(t, memFs.MkdirAll("dir", 0755))
+		writeFile(t, memFs, "dir/file.txt", "key1", 0600)
+		b := backup{suffix: ".{timestamp}.bak", time: time.Date(2020, 3, 14, 15, 9, 26, 0, time.UTC)}
+
+		assert.NoError(t, replaceInPlace(b)(memFs, r, "dir/file.txt"))
+
+		assert.Equal(t, "value1", readFile(t, memFs, "dir/file.txt"))
+		assert.Equal(t, "key1", readFile(t, memFs, "dir/file.txt.20200314150926.bak"))
+		mode, err := memFs.FileMode("dir/file.txt.20200314150926.bak")
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), mode)
+	})
+
+	t.Run("should keep backups in a directory mirroring the tree", func(t *testing.T) {
+		memFs := fs.NewMemFs()
+		assert.NoError(t, memFs.MkdirAll("src/nested", 0755))
+		writeFile(t, memFs, "src/nested/file.txt", "key1", 0644)
+
+		assert.NoError(t, replaceInPlace(backup{dir: "backups"})(memFs, r, "src/nested/file.txt"))
+
+		assert.Equal(t, "value1", readFile(t, memFs, "src/nested/file.txt"))
+		assert.Equal(t, "key1", readFile(t, memFs, "backups/src/nested/file.txt"))
+	})
+}
+
+func TestWithDefaultBackupSuffix(t *testing.T) {
+	args := withDefaultBackupSuffix([]string{"replace-text", "--backup", "-i", "--backup=.bak", "--", "--backup"})
+
+	assert.Equal(t, []string{"replace-text", "--backup=.orig", "-i", "--backup=.bak", "--", "--backup"}, args)
+}
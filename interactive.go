@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/aswinkarthik/replace-text/diff"
+	"github.com/aswinkarthik/replace-text/fs"
+	"github.com/aswinkarthik/replace-text/replacer"
+)
+
+const interactiveHelp = `y - replace this match
+n - do not replace this match
+a - replace this and all later matches in the file
+q - quit; do not replace this or any later match
+e - edit the replacement of this match
+? - print help
+`
+
+// confirmation asks for a decision on every match before it is replaced.
+// Prompts are written to prompts, and answers are read one per line from answers.
+type confirmation struct {
+	answers *bufio.Reader
+	prompts io.Writer
+	context int
+	// quit is set once no more matches are to be replaced in any file
+	quit bool
+}
+
+// replaceInteractively returns a function replacing only the matches accepted
+// in a file. Files are edited in place with the backup if inPlace is set,
+// or else written to stdout.
+func replaceInteractively(c *confirmation, inPlace bool, b backup) func(fs fs.Fs, r *replacer.Replacer, path string) error {
+	return func(fs fs.Fs, r *replacer.Replacer, path string) error {
+		file, err := openInput(fs, path)
+		if err != nil {
+			return fmt.Errorf("error opening input file: %v", err)
+		}
+
+		original, err := ioutil.ReadAll(file)
+		_ = file.Close()
+		if err != nil {
+			return fmt.Errorf("error reading input file %s: %v", path, err)
+		}
+
+		edits, err := r.Find(path, bytes.NewReader(original))
+		if err != nil {
+			return fmt.Errorf("error finding matches in input file: %v", err)
+		}
+
+		accepted, err := c.confirm(path, original, edits)
+		if err != nil {
+			return err
+		}
+
+		if !inPlace {
+			return replacer.Apply(bytes.NewReader(original), stdout, accepted)
+		}
+
+		if len(accepted) == 0 {
+			return nil
+		}
+
+		return rewriteInPlace(fs, b, path, func(reader io.Reader, writer io.Writer) error {
+			return replacer.Apply(reader, writer, accepted)
+		})
+	}
+}
+
+// confirm shows every edit of the file with the lines around it,
+// and returns the edits accepted.
+func (c *confirmation) confirm(path string, original []byte, edits []replacer.Edit) ([]replacer.Edit, error) {
+	var accepted []replacer.Edit
+	all := false
+
+	for _, edit := range edits {
+		if c.quit {
+			break
+		}
+
+		if all {
+			accepted = append(accepted, edit)
+			continue
+		}
+
+		line := bytes.Count(original[:edit.Start], []byte{'\n'}) + 1
+		if _, err := fmt.Fprintf(c.prompts, "%s:%d\n", path, line); err != nil {
+			return nil, fmt.Errorf("error writing prompt: %v", err)
+		}
+		if err := diff.Hunks(c.prompts, original, []replacer.Edit{edit}, c.context); err != nil {
+			return nil, err
+		}
+
+		decided := false
+		for !decided {
+			answer, err := c.ask("Replace this match [y,n,a,q,e,?]? ")
+			if err != nil {
+				return nil, err
+			}
+
+			decided = true
+			switch answer {
+			case "y":
+				accepted = append(accepted, edit)
+			case "n":
+			case "a":
+				accepted = append(accepted, edit)
+				all = true
+			case "q":
+				c.quit = true
+			case "e":
+				replacement, err := c.ask("Replacement: ")
+				if err != nil {
+					return nil, err
+				}
+				if !c.quit {
+					edit.Replacement = replacement
+					accepted = append(accepted, edit)
+				}
+			default:
+				decided = false
+				if _, err := io.WriteString(c.prompts, interactiveHelp); err != nil {
+					return nil, fmt.Errorf("error writing prompt: %v", err)
+				}
+			}
+		}
+	}
+
+	return accepted, nil
+}
+
+// ask writes the prompt and reads a line as the answer.
+// Running out of answers is the same as quitting.
+func (c *confirmation) ask(prompt string) (string, error) {
+	if _, err := io.WriteString(c.prompts, prompt); err != nil {
+		return "", fmt.Errorf("error writing prompt: %v", err)
+	}
+
+	answer, err := c.answers.ReadString('\n')
+	if err == io.EOF && answer == "" {
+		c.quit = true
+		return "q", nil
+	}
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("error reading answer: %v", err)
+	}
+
+	return strings.TrimRight(answer, "\r\n"), nil
+}
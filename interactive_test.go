@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/aswinkarthik/replace-text/fs"
+	"github.com/aswinkarthik/replace-text/replacer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplaceInteractively(t *testing.T) {
+	r, err := replacer.NewReplacer(map[string]string{"key": "value"})
+	assert.NoError(t, err)
+
+	newConfirmation := func(answers string) *confirmation {
+		return &confirmation{
+			answers: bufio.NewReader(strings.NewReader(answers)),
+			prompts: ioutil.Discard,
+		}
+	}
+
+	t.Run("should replace only accepted matches in place", func(t *testing.T) {
+		memFs := fs.NewMemFs()
+		writeFile(t, memFs, "file.txt", "key key key key", 0644)
+
+		c := newConfirmation("y\nn\ne\nnew\n?\nq\n")
+		assert.NoError(t, replaceInteractively(c, true, backup{})(memFs, r, "file.txt"))
+		assert.Equal(t, "value key new key", readFile(t, memFs, "file.txt"))
+		assert.True(t, c.quit)
+	})
+
+	t.Run("should replace all later matches in the file", func(t *testing.T) {
+		memFs := fs.NewMemFs()
+		writeFile(t, memFs, "a.txt", "key key key", 0644)
+		writeFile(t, memFs, "b.txt", "key key", 0644)
+
+		c := newConfirmation("n\na\ny\n")
+		replace := replaceInteractively(c, true, backup{})
+		assert.NoError(t, replace(memFs, r, "a.txt"))
+		assert.NoError(t, replace(memFs, r, "b.txt"))
+		assert.Equal(t, "key value value", readFile(t, memFs, "a.txt"))
+		assert.Equal(t, "value key", readFile(t, memFs, "b.txt"))
+	})
+
+	t.Run("should write files to stdout without replacing after quitting", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		defer withStdio(strings.NewReader(""), out)()
+
+		memFs := fs.NewMemFs()
+		writeFile(t, memFs, "a.txt", "key key\n", 0644)
+		writeFile(t, memFs, "b.txt", "key\n", 0644)
+
+		// Running out of answers is the same as quitting
+		c := newConfirmation("y\n")
+		replace := replaceInteractively(c, false, backup{})
+		assert.NoError(t, replace(memFs, r, "a.txt"))
+		assert.NoError(t, replace(memFs, r, "b.txt"))
+		assert.Equal(t, "value key\nkey\n", out.String())
+		assert.Equal(t, "key key\n", readFile(t, memFs, "a.txt"))
+	})
+}
+
+func TestConfirmation_Confirm(t *testing.T) {
+	t.Run("should show the match with the lines around it", func(t *testing.T) {
+		prompts := &bytes.Buffer{}
+		c := &confirmation{
+			answers: bufio.NewReader(strings.NewReader("y\n")),
+			prompts: prompts,
+			context: 1,
+		}
+
+		edits := []replacer.Edit{{Start: 2, End: 5, Replacement: "value"}}
+		accepted, err := c.confirm("file.txt", []byte("a\nkey\nb\nc\n"), edits)
+		assert.NoError(t, err)
+		assert.Equal(t, edits, accepted)
+		assert.Equal(t, "file.txt:2\n@@ -1,3 +1,3 @@\n a\n-key\n+value\n b\nReplace this match [y,n,a,q,e,?]? ", prompts.String())
+	})
+}
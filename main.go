@@ -1,13 +1,22 @@
 package main
 
 import (
-	"encoding/json"
+	"bufio"
+	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/aswinkarthik/replace-text/diff"
 	"github.com/aswinkarthik/replace-text/fs"
+	"github.com/aswinkarthik/replace-text/glob"
+	"github.com/aswinkarthik/replace-text/patterns"
 	"github.com/aswinkarthik/replace-text/replacer"
+	"github.com/aswinkarthik/replace-text/walk"
 	cli "github.com/urfave/cli/v2"
 )
 
@@ -18,8 +27,65 @@ const (
 	// in the input
 	ExitCodeValidationError = 2
 
-	flagPatternsFile            = "patterns-file"
+	flagPatternsFile = "patterns-file"
+	flagPatternsFmt  = "patterns-format"
+	flagStrictMerge  = "strict-merge"
+	flagFlatten      = "flatten"
+	flagFindColumn   = "find-column"
+	flagReplaceCol   = "replace-column"
+	flagMatchPolicy  = "match-policy"
+	flagIgnoreCase   = "ignore-case"
+	flagUnicodeCase  = "unicode-case"
+	flagWholeWord    = "whole-word"
+	flagWordChars    = "word-chars"
+	flagTemplate     = "template"
+	flagMaxCount     = "max-count"
+	flagNth          = "nth"
+	flagLast         = "last"
+	flagLimitScope   = "limit-scope"
+	flagInPlace      = "in-place"
+	flagBackup       = "backup"
+	flagBackupDir    = "backup-dir"
+	flagInclude      = "include"
+	flagExclude      = "exclude"
+	flagMaxDepth     = "max-depth"
+	flagHidden       = "hidden"
+	flagNoIgnore     = "no-ignore"
+	flagDryRun       = "dry-run"
+	flagDiff         = "diff"
+	flagContext      = "context"
+	flagInteractive  = "interactive"
+	flagOutputDir    = "output-dir"
+	flagExpression   = "expression"
+	flagSeparator    = "separator"
+	flagFrom         = "from"
+	flagTo           = "to"
+	flagEnv          = "env"
+	flagEnvFile      = "env-file"
+	flagEnvAllow     = "env-allow"
+	flagPrefix       = "prefix"
+	flagSuffix       = "suffix"
+	flagDelimiters   = "delimiters"
+	flagDelimSpaces  = "delimiter-spaces"
+
 	metadataValidationErrorsKey = "validation-errors"
+
+	limitScopeFile = "file"
+	limitScopeRun  = "run"
+
+	// stdinPath is the path of input to be read from stdin
+	stdinPath = "-"
+	// stdinLabel names stdin in the header of diffs
+	stdinLabel = "<stdin>"
+)
+
+var (
+	// stdin is the reader used for input paths that are stdinPath
+	stdin io.Reader = os.Stdin
+	// stdout is where replaced content is written, unless editing in place
+	stdout io.Writer = os.Stdout
+	// stderr is where prompts of the interactive mode are written
+	stderr io.Writer = os.Stderr
 )
 
 func main() {
@@ -29,20 +95,188 @@ func main() {
 		Name:            AppName,
 		Usage:           "Find & Replace multiple texts in files",
 		ArgsUsage:       "[PATH ...]",
+		Description:     "Input is read from stdin if no PATH is given, or if PATH is " + stdinPath + ". Directories are walked recursively",
 		Action:          run(fs),
 		Writer:          fs.DevNull(),
 		HideHelpCommand: true,
 		Flags: []cli.Flag{
-			&cli.StringFlag{
+			&cli.StringSliceFlag{
 				Name:    flagPatternsFile,
 				Aliases: []string{"p"},
-				Usage:   "Load find & replace patterns from a JSON file",
+				Usage:   "Load find & replace patterns from a JSON, YAML, TOML, CSV or TSV file, holding an object or an array of patterns. Can be repeated, and later files override earlier ones. A directory loads its patterns files in lexical order",
 				EnvVars: []string{"PATTERNS_FILE", "REPLACE_TEXT_PATTERNS_FILE"},
 			},
+			&cli.StringFlag{
+				Name:  flagPatternsFmt,
+				Usage: "Format of the patterns file: json, yaml, toml, csv or tsv. Detected by the file extension by default, falling back to json",
+			},
+			&cli.BoolFlag{
+				Name:  flagStrictMerge,
+				Usage: "Fail when patterns files give different replacements for the same find text, instead of overriding earlier files",
+			},
+			&cli.BoolFlag{
+				Name:  flagFlatten,
+				Usage: `Flatten nested maps of the patterns file into patterns with dotted keys, like "db.host"`,
+			},
+			&cli.StringFlag{
+				Name:  flagFindColumn,
+				Usage: "Name of the column holding find texts in the header row of CSV and TSV patterns files, instead of the first column",
+				Value: patterns.DefaultFindColumn,
+			},
+			&cli.StringFlag{
+				Name:  flagReplaceCol,
+				Usage: "Name of the column holding replacements in the header row of CSV and TSV patterns files, instead of the second column",
+				Value: patterns.DefaultReplaceColumn,
+			},
+			&cli.StringSliceFlag{
+				Name:    flagExpression,
+				Aliases: []string{"e"},
+				Usage:   `Replace the find text with the replacement of an "old=new" expression, overriding the patterns file. Can be repeated`,
+			},
+			&cli.StringFlag{
+				Name:  flagSeparator,
+				Usage: `Separator of the find text and the replacement in expressions. It is escaped with \`,
+				Value: patterns.DefaultSeparator,
+			},
+			&cli.StringSliceFlag{
+				Name:  flagFrom,
+				Usage: "Find text replaced by the --" + flagTo + " at the same position, overriding expressions. Can be repeated",
+			},
+			&cli.StringSliceFlag{
+				Name:  flagTo,
+				Usage: "Replacement of the --" + flagFrom + " at the same position. Can be repeated",
+			},
+			&cli.BoolFlag{
+				Name:  flagEnv,
+				Usage: "Replace ${VAR}, $VAR, ${VAR:-default} and ${VAR:?error} placeholders with variables of the environment",
+			},
+			&cli.StringFlag{
+				Name:  flagEnvFile,
+				Usage: "Read variables for placeholders from a .env file instead of the environment, implying --" + flagEnv,
+			},
+			&cli.StringSliceFlag{
+				Name:  flagEnvAllow,
+				Usage: "Replace placeholders of only the named variable with --" + flagEnv + ". Can be repeated",
+			},
+			&cli.StringFlag{
+				Name:  flagPrefix,
+				Usage: `Text before every find text of the patterns file and inline patterns, like "{{"`,
+			},
+			&cli.StringFlag{
+				Name:  flagSuffix,
+				Usage: `Text after every find text of the patterns file and inline patterns, like "}}"`,
+			},
+			&cli.StringFlag{
+				Name:  flagDelimiters,
+				Usage: `Prefix and suffix of find texts separated by a comma, like "{{,}}"`,
+			},
+			&cli.BoolFlag{
+				Name:  flagDelimSpaces,
+				Usage: "Match spaces and tabs between the prefix or suffix and find texts, like {{ key }}",
+			},
+			&cli.StringFlag{
+				Name:  flagMatchPolicy,
+				Usage: "Policy to pick between overlapping matches: leftmost-longest, leftmost-first or longest. Only leftmost-first lets the order of patterns, like that of an array patterns file, decide between matches at the same position",
+				Value: replacer.LeftmostLongest.String(),
+			},
+			&cli.BoolFlag{
+				Name:  flagIgnoreCase,
+				Usage: "Ignore case of ASCII letters while matching",
+			},
+			&cli.BoolFlag{
+				Name:  flagUnicodeCase,
+				Usage: "Ignore case of all letters using Unicode case folding while matching",
+			},
+			&cli.BoolFlag{
+				Name:    flagWholeWord,
+				Aliases: []string{"w"},
+				Usage:   "Replace patterns only when they match whole words",
+			},
+			&cli.StringFlag{
+				Name:  flagWordChars,
+				Usage: `Characters that form words: "word" for letters, digits and '_', "identifier" to include '$', or a list of characters`,
+				Value: "word",
+			},
+			&cli.BoolFlag{
+				Name:  flagTemplate,
+				Usage: "Render every replacement as a Go text/template with the match as context",
+			},
+			&cli.IntFlag{
+				Name:  flagMaxCount,
+				Usage: "Replace only the first N occurrences of all patterns together",
+			},
+			&cli.IntFlag{
+				Name:  flagNth,
+				Usage: "Replace only the Nth occurrence of all patterns together",
+			},
+			&cli.BoolFlag{
+				Name:  flagLast,
+				Usage: "Replace only the last occurrence of all patterns together",
+			},
+			&cli.StringFlag{
+				Name:  flagLimitScope,
+				Usage: `Count occurrences for limits in each "file" or across the whole "run"`,
+				Value: limitScopeFile,
+			},
+			&cli.BoolFlag{
+				Name:    flagInPlace,
+				Aliases: []string{"i"},
+				Usage:   "Edit files in place instead of printing to stdout",
+			},
+			&cli.StringFlag{
+				Name:  flagBackup,
+				Usage: "Keep the original of files edited in place, given as --" + flagBackup + "[=SUFFIX] (default suffix: " + defaultBackupSuffix + "). " + backupTimestamp + " in SUFFIX is replaced with the current time",
+			},
+			&cli.StringFlag{
+				Name:  flagBackupDir,
+				Usage: "Keep the originals of files edited in place under the directory, mirroring their paths",
+			},
+			&cli.StringSliceFlag{
+				Name:  flagInclude,
+				Usage: "Replace only files matching the glob while walking directories. Can be repeated",
+			},
+			&cli.StringSliceFlag{
+				Name:  flagExclude,
+				Usage: "Skip files and directories matching the glob while walking directories. Can be repeated",
+			},
+			&cli.IntFlag{
+				Name:  flagMaxDepth,
+				Usage: "Descend at most N levels below directories, where 1 is their own files. 0 means no limit",
+			},
+			&cli.BoolFlag{
+				Name:  flagHidden,
+				Usage: "Walk into hidden files and directories, whose names start with a dot",
+			},
+			&cli.BoolFlag{
+				Name:  flagNoIgnore,
+				Usage: "Walk into files ignored by .gitignore, .ignore, .replace-text-ignore and .git/info/exclude",
+			},
+			&cli.BoolFlag{
+				Name:  flagDryRun,
+				Usage: "Print the paths of files that would change instead of replacing anything",
+			},
+			&cli.BoolFlag{
+				Name:  flagDiff,
+				Usage: "Print the changes of --" + flagDryRun + " as a unified diff, which can be applied with patch -p1",
+			},
+			&cli.IntFlag{
+				Name:    flagContext,
+				Aliases: []string{"U"},
+				Usage:   "Number of unchanged lines shown around changes in the diff and interactive prompts",
+				Value:   diff.DefaultContext,
+			},
+			&cli.BoolFlag{
+				Name:  flagInteractive,
+				Usage: "Ask whether to replace every match, answering y (yes), n (no), a (all in file), q (quit) or e (edit replacement)",
+			},
+			&cli.StringFlag{
+				Name:  flagOutputDir,
+				Usage: "Write files to the same path under the directory instead of printing them, copying files without matches as they are",
+			},
 		},
 		Before: parseInput(fs),
 	}
-	if err := app.Run(os.Args); err != nil {
+	if err := app.Run(withDefaultBackupSuffix(os.Args)); err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "%s: %v\n", AppName, err)
 		os.Exit(1)
 	}
@@ -50,30 +284,70 @@ func main() {
 
 func run(fs fs.Fs) func(ctx *cli.Context) error {
 	return func(ctx *cli.Context) error {
-		patternsFileName := ctx.String(flagPatternsFile)
-		patternsFile, err := fs.Open(patternsFileName)
+		vars, err := loadVariables(fs, ctx)
 		if err != nil {
-			return fmt.Errorf("error opening patterns-file: %v", err)
+			return err
 		}
 
-		var patterns map[string]string
-		if err := json.NewDecoder(patternsFile).Decode(&patterns); err != nil {
-			return fmt.Errorf("error decoding patterns file: %v", err)
+		findReplacePatterns, err := loadPatterns(fs, ctx, vars)
+		if err != nil {
+			return err
 		}
 
-		r, err := replacer.NewReplacer(patterns)
+		opts, err := replacerOptions(ctx)
+		if err != nil {
+			return err
+		}
+		if vars != nil {
+			opts = append(opts, replacer.WithEnv(vars.env))
+		}
+
+		r, err := replacer.NewPatternReplacer(findReplacePatterns, opts...)
 		if err != nil {
 			return fmt.Errorf("error creating replacer for given patterns: %v", err)
 		}
 
-		for _, inputFile := range ctx.Args().Slice() {
-			file, err := fs.Open(inputFile)
-			if err != nil {
-				return fmt.Errorf("error opening input file: %v", err)
+		walkOpts, err := walkOptions(ctx)
+		if err != nil {
+			return err
+		}
+
+		inputFiles, err := expandInputPaths(fs, inputPaths(ctx), walkOpts)
+		if err != nil {
+			return err
+		}
+
+		if ctx.String(flagLimitScope) == limitScopeRun && needsTotals(ctx, findReplacePatterns) {
+			if err := countFiles(fs, r, inputFiles); err != nil {
+				return err
 			}
+		}
+
+		b := backup{
+			suffix: ctx.String(flagBackup),
+			dir:    ctx.String(flagBackupDir),
+			time:   time.Now(),
+		}
 
-			if err := r.Replace(file, os.Stdout); err != nil {
-				return fmt.Errorf("error finding and replacing content in input file: %v", err)
+		replace := replaceFile
+		switch {
+		case ctx.Bool(flagDryRun):
+			replace = dryRun(ctx.Bool(flagDiff), ctx.Int(flagContext))
+		case ctx.Bool(flagInteractive):
+			replace = replaceInteractively(&confirmation{
+				answers: bufio.NewReader(stdin),
+				prompts: stderr,
+				context: ctx.Int(flagContext),
+			}, ctx.Bool(flagInPlace), b)
+		case ctx.Bool(flagInPlace):
+			replace = replaceInPlace(b)
+		case ctx.IsSet(flagOutputDir):
+			replace = replaceToDir(ctx.String(flagOutputDir))
+		}
+
+		for _, inputFile := range inputFiles {
+			if err := replace(fs, r, inputFile); err != nil {
+				return err
 			}
 		}
 
@@ -81,46 +355,582 @@ func run(fs fs.Fs) func(ctx *cli.Context) error {
 	}
 }
 
+// loadPatterns merges the patterns substituting variables, the patterns of
+// the patterns files and the inline patterns. Find texts of the patterns files
+// and the inline patterns are wrapped in the delimiters.
+// Patterns overriding different ones are reported on stderr,
+// as is the source of every pattern but placeholders when there are several patterns files.
+func loadPatterns(fs fs.Fs, ctx *cli.Context, vars *variables) ([]replacer.Pattern, error) {
+	files, err := patternsFiles(fs, ctx.StringSlice(flagPatternsFile))
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := delimiters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var fileSources []patterns.Source
+	for _, patternsFileName := range files {
+		source, err := loadPatternsFile(fs, ctx, patternsFileName)
+		if err != nil {
+			return nil, err
+		}
+		source.Patterns = d.Wrap(source.Patterns)
+		fileSources = append(fileSources, source)
+	}
+
+	if ctx.Bool(flagStrictMerge) {
+		if _, conflicts := patterns.Merge(fileSources...); len(conflicts) > 0 {
+			lines := make([]string, 0, len(conflicts))
+			for _, conflict := range conflicts {
+				lines = append(lines, conflict.String())
+			}
+			return nil, fmt.Errorf("patterns files disagree:\n%s", strings.Join(lines, "\n"))
+		}
+	}
+
+	// Placeholders of variables come first, so that patterns files override them
+	var sources []patterns.Source
+	var envSource string
+	if vars != nil {
+		envPatterns, err := patterns.Env(vars.env, ctx.StringSlice(flagEnvAllow))
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, patterns.Source{Name: vars.source, Patterns: envPatterns})
+		envSource = vars.source
+	}
+	sources = append(sources, fileSources...)
+
+	inline, err := inlinePatterns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range inline {
+		inline[i].Patterns = d.Wrap(inline[i].Patterns)
+	}
+
+	origins, conflicts := patterns.MergeOrigins(append(sources, inline...)...)
+	for _, conflict := range conflicts {
+		_, _ = fmt.Fprintf(stderr, "%s: %s\n", AppName, conflict)
+	}
+
+	merged := make([]replacer.Pattern, 0, len(origins))
+	for _, origin := range origins {
+		if len(files) > 1 && origin.Source != envSource {
+			_, _ = fmt.Fprintf(stderr, "%s: %s\n", AppName, origin)
+		}
+		merged = append(merged, origin.Pattern)
+	}
+
+	return merged, nil
+}
+
+// variables are substituted in placeholders with --env,
+// and are the .Env of templates
+type variables struct {
+	// source names where the variables were read from
+	source string
+	env    map[string]string
+}
+
+// loadVariables reads the variables of the .env file, or of the environment
+// if no file is given. It returns nil if placeholders are not substituted.
+func loadVariables(fs fs.Fs, ctx *cli.Context) (*variables, error) {
+	if !ctx.Bool(flagEnv) && !ctx.IsSet(flagEnvFile) {
+		return nil, nil
+	}
+
+	path := ctx.String(flagEnvFile)
+	if path == "" {
+		return &variables{source: "the environment", env: replacer.Environ()}, nil
+	}
+
+	envFile, err := fs.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening env-file: %v", err)
+	}
+	defer envFile.Close()
+
+	env, err := patterns.ParseEnvFile(envFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &variables{source: path, env: env}, nil
+}
+
+// loadPatternsFile decodes the patterns of the patterns file at path
+func loadPatternsFile(fs fs.Fs, ctx *cli.Context, path string) (patterns.Source, error) {
+	patternsFile, err := fs.Open(path)
+	if err != nil {
+		return patterns.Source{}, fmt.Errorf("error opening patterns-file: %v", err)
+	}
+	defer patternsFile.Close()
+
+	opts, err := decodeOptions(ctx, path)
+	if err != nil {
+		return patterns.Source{}, err
+	}
+
+	filePatterns, err := patterns.Decode(patternsFile, opts...)
+	if _, nested := err.(*patterns.NestedMapError); nested {
+		return patterns.Source{}, fmt.Errorf("error loading patterns-file %s: %v, use --%s to replace its keys joined with dots", path, err, flagFlatten)
+	}
+	if err != nil {
+		return patterns.Source{}, fmt.Errorf("error loading patterns-file %s: %v", path, err)
+	}
+
+	return patterns.Source{Name: path, Patterns: filePatterns}, nil
+}
+
+// patternsFiles replaces directories in paths with the patterns files in them,
+// which are the files with the extension of a patterns format, in lexical order.
+// Hidden files and subdirectories are skipped.
+func patternsFiles(fs fs.Fs, paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		if !fs.IsDir(path) {
+			files = append(files, path)
+			continue
+		}
+
+		infos, err := fs.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading patterns directory: %v", err)
+		}
+
+		found := false
+		for _, info := range infos {
+			name := info.Name()
+			if info.IsDir() || strings.HasPrefix(name, ".") {
+				continue
+			}
+			if _, err := patterns.ParseFormat(strings.TrimPrefix(filepath.Ext(name), ".")); err != nil {
+				continue
+			}
+
+			files = append(files, filepath.Join(path, name))
+			found = true
+		}
+
+		if !found {
+			return nil, fmt.Errorf(`directory "%s" has no patterns files`, path)
+		}
+	}
+
+	return files, nil
+}
+
+// decodeOptions returns the options to decode the patterns file at path.
+// Its format is detected by its extension unless it is given.
+func decodeOptions(ctx *cli.Context, path string) ([]patterns.DecodeOption, error) {
+	format := patterns.FormatOf(path)
+	if name := ctx.String(flagPatternsFmt); name != "" {
+		var err error
+		if format, err = patterns.ParseFormat(name); err != nil {
+			return nil, err
+		}
+	}
+
+	opts := []patterns.DecodeOption{patterns.WithFormat(format)}
+	if ctx.Bool(flagFlatten) {
+		opts = append(opts, patterns.WithFlatten())
+	}
+
+	if ctx.IsSet(flagFindColumn) || ctx.IsSet(flagReplaceCol) {
+		opts = append(opts, patterns.WithColumns(ctx.String(flagFindColumn), ctx.String(flagReplaceCol)))
+	}
+
+	return opts, nil
+}
+
+// delimiters returns the delimiters wrapping find texts,
+// given either as a prefix and a suffix or together
+func delimiters(ctx *cli.Context) (patterns.Delimiters, error) {
+	d := patterns.Delimiters{
+		Prefix: ctx.String(flagPrefix),
+		Suffix: ctx.String(flagSuffix),
+		Spaces: ctx.Bool(flagDelimSpaces),
+	}
+
+	if ctx.IsSet(flagDelimiters) {
+		if ctx.IsSet(flagPrefix) || ctx.IsSet(flagSuffix) {
+			return d, fmt.Errorf("--%s cannot be used with --%s or --%s", flagDelimiters, flagPrefix, flagSuffix)
+		}
+
+		parsed, err := patterns.ParseDelimiters(ctx.String(flagDelimiters))
+		if err != nil {
+			return d, err
+		}
+		d.Prefix, d.Suffix = parsed.Prefix, parsed.Suffix
+	}
+
+	if d.Spaces && d.Prefix == "" && d.Suffix == "" {
+		return d, fmt.Errorf("--%s can only be used with --%s, --%s or --%s", flagDelimSpaces, flagPrefix, flagSuffix, flagDelimiters)
+	}
+
+	return d, nil
+}
+
+// inlinePatterns returns a source for every pattern given with flags.
+// Pairs of --from and --to come after expressions, so that they override them.
+func inlinePatterns(ctx *cli.Context) ([]patterns.Source, error) {
+	var sources []patterns.Source
+	for _, expr := range ctx.StringSlice(flagExpression) {
+		p, err := patterns.ParseExpression(expr, ctx.String(flagSeparator))
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, patterns.Source{
+			Name:     fmt.Sprintf("-e %q", expr),
+			Patterns: []replacer.Pattern{p},
+		})
+	}
+
+	from, to := ctx.StringSlice(flagFrom), ctx.StringSlice(flagTo)
+	if len(from) != len(to) {
+		return nil, fmt.Errorf("--%s is given %d times but --%s is given %d times", flagFrom, len(from), flagTo, len(to))
+	}
+
+	for i := range from {
+		sources = append(sources, patterns.Source{
+			Name:     fmt.Sprintf("--%s %q --%s %q", flagFrom, from[i], flagTo, to[i]),
+			Patterns: []replacer.Pattern{patterns.Entry{Replace: to[i]}.Pattern(from[i])},
+		})
+	}
+
+	return sources, nil
+}
+
+// inputPaths returns the paths of input given as arguments.
+// Input is read from stdin if no paths are given.
+func inputPaths(ctx *cli.Context) []string {
+	if ctx.NArg() == 0 {
+		return []string{stdinPath}
+	}
+
+	return ctx.Args().Slice()
+}
+
+// expandInputPaths replaces directories in paths with the files found
+// by walking them. Other paths are kept as they are.
+func expandInputPaths(fs fs.Fs, paths []string, opts walk.Options) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		if path == stdinPath || !fs.IsDir(path) {
+			files = append(files, path)
+			continue
+		}
+
+		err := walk.Walk(fs, path, opts, func(file string) error {
+			files = append(files, file)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// openInput opens the input file at path, or stdin if path is stdinPath
+func openInput(fs fs.Fs, path string) (io.ReadCloser, error) {
+	if path == stdinPath {
+		return ioutil.NopCloser(stdin), nil
+	}
+
+	return fs.Open(path)
+}
+
+func replaceFile(fs fs.Fs, r *replacer.Replacer, inputFile string) error {
+	file, err := openInput(fs, inputFile)
+	if err != nil {
+		return fmt.Errorf("error opening input file: %v", err)
+	}
+	defer file.Close()
+
+	// Content is copied as is when limits leave nothing to replace in a file
+	err = r.ReplaceFile(inputFile, file, stdout)
+	if err != nil && err != replacer.ErrNoMatchesFound {
+		return fmt.Errorf("error finding and replacing content in input file: %v", err)
+	}
+
+	return nil
+}
+
+// countFiles counts occurrences in the files for limits replacing
+// the last occurrence across the whole run.
+// Stdin is read into memory, so that it can be read again for replacing.
+func countFiles(fs fs.Fs, r *replacer.Replacer, inputFiles []string) error {
+	for _, inputFile := range inputFiles {
+		if inputFile == stdinPath {
+			data, err := ioutil.ReadAll(stdin)
+			if err != nil {
+				return fmt.Errorf("error reading stdin: %v", err)
+			}
+
+			if err := r.Count(bytes.NewReader(data)); err != nil {
+				return fmt.Errorf("error counting matches in stdin: %v", err)
+			}
+			stdin = bytes.NewReader(data)
+			continue
+		}
+
+		file, err := fs.Open(inputFile)
+		if err != nil {
+			return fmt.Errorf("error opening input file: %v", err)
+		}
+
+		err = r.Count(file)
+		_ = file.Close()
+		if err != nil {
+			return fmt.Errorf("error counting matches in input file: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// needsTotals returns true if any limit replaces only the last occurrence
+func needsTotals(ctx *cli.Context, findReplacePatterns []replacer.Pattern) bool {
+	if ctx.Bool(flagLast) {
+		return true
+	}
+
+	for _, p := range findReplacePatterns {
+		if p.Last {
+			return true
+		}
+	}
+
+	return false
+}
+
+func walkOptions(ctx *cli.Context) (walk.Options, error) {
+	opts := walk.Options{
+		MaxDepth: ctx.Int(flagMaxDepth),
+		Hidden:   ctx.Bool(flagHidden),
+		Ignore:   !ctx.Bool(flagNoIgnore),
+	}
+
+	if opts.MaxDepth < 0 {
+		return opts, fmt.Errorf("--%s cannot be negative", flagMaxDepth)
+	}
+
+	for _, pattern := range ctx.StringSlice(flagInclude) {
+		g, err := glob.Compile(pattern)
+		if err != nil {
+			return opts, err
+		}
+		opts.Include = append(opts.Include, g)
+	}
+
+	for _, pattern := range ctx.StringSlice(flagExclude) {
+		g, err := glob.Compile(pattern)
+		if err != nil {
+			return opts, err
+		}
+		opts.Exclude = append(opts.Exclude, g)
+	}
+
+	return opts, nil
+}
+
+func replacerOptions(ctx *cli.Context) ([]replacer.Option, error) {
+	policy, err := replacer.ParseMatchPolicy(ctx.String(flagMatchPolicy))
+	if err != nil {
+		return nil, err
+	}
+
+	folding := replacer.CaseSensitive
+	if ctx.Bool(flagIgnoreCase) {
+		folding = replacer.FoldASCII
+	}
+	if ctx.Bool(flagUnicodeCase) {
+		folding = replacer.FoldUnicode
+	}
+
+	opts := []replacer.Option{
+		replacer.WithMatchPolicy(policy),
+		replacer.WithCaseFolding(folding),
+	}
+
+	switch wordChars := ctx.String(flagWordChars); wordChars {
+	case "word":
+		opts = append(opts, replacer.WithWordChars(replacer.DefaultWordChars))
+	case "identifier":
+		opts = append(opts, replacer.WithWordChars(replacer.IdentifierWordChars))
+	default:
+		opts = append(opts, replacer.WithWordChars(replacer.WordCharsOf(wordChars)))
+	}
+
+	if ctx.Bool(flagWholeWord) {
+		opts = append(opts, replacer.WithWholeWords())
+	}
+
+	if ctx.Bool(flagTemplate) {
+		opts = append(opts, replacer.WithTemplates())
+	}
+
+	d, err := delimiters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if d.Spaces {
+		opts = append(opts, replacer.WithDelimiterSpaces(d.Prefix, d.Suffix))
+	}
+
+	for _, name := range []string{flagMaxCount, flagNth} {
+		if ctx.Int(name) < 0 {
+			return nil, fmt.Errorf("--%s cannot be negative", name)
+		}
+	}
+
+	opts = append(opts, replacer.WithLimit(replacer.Limit{
+		MaxCount: ctx.Int(flagMaxCount),
+		Nth:      ctx.Int(flagNth),
+		Last:     ctx.Bool(flagLast),
+	}))
+
+	switch scope := ctx.String(flagLimitScope); scope {
+	case limitScopeFile:
+		opts = append(opts, replacer.WithLimitScope(replacer.PerInput))
+	case limitScopeRun:
+		opts = append(opts, replacer.WithLimitScope(replacer.PerRun))
+	default:
+		return nil, fmt.Errorf("unknown limit scope %q", scope)
+	}
+
+	return opts, nil
+}
+
 func parseInput(fs fs.Fs) func(ctx *cli.Context) error {
 	return func(ctx *cli.Context) error {
-		flagPreset := ctx.IsSet(flagPatternsFile)
-
-		if flagPreset {
-			filename := ctx.String(flagPatternsFile)
-			if !fs.IsFile(filename) {
-				ctx.App.Metadata[metadataValidationErrorsKey] = true
-				return cli.Exit(
-					fmt.Sprintf(`%s: file "%s" does not exist`, AppName, filename),
-					ExitCodeValidationError,
-				)
+		for _, filename := range ctx.StringSlice(flagPatternsFile) {
+			if !fs.IsFile(filename) && !fs.IsDir(filename) {
+				return validationError(ctx, `file "%s" does not exist`, filename)
 			}
+		}
 
+		if _, err := patternsFiles(fs, ctx.StringSlice(flagPatternsFile)); err != nil {
+			return validationError(ctx, "%v", err)
+		}
+
+		if _, err := decodeOptions(ctx, ""); err != nil {
+			return validationError(ctx, "%v", err)
+		}
+
+		inline, err := inlinePatterns(ctx)
+		if err != nil {
+			return validationError(ctx, "%v", err)
 		}
 
-		if ctx.NArg() > 0 {
-			for _, arg := range ctx.Args().Slice() {
-				if !fs.IsFile(arg) {
-					ctx.App.Metadata[metadataValidationErrorsKey] = true
-					return cli.Exit(
-						fmt.Sprintf(`%s: file "%s" does not exist`, AppName, arg),
-						ExitCodeValidationError,
-					)
-				}
+		envMode := ctx.Bool(flagEnv) || ctx.IsSet(flagEnvFile)
+		if len(ctx.StringSlice(flagPatternsFile)) == 0 && len(inline) == 0 && !envMode {
+			return validationError(ctx, "no patterns given, use --%s, -e, --%s and --%s or --%s", flagPatternsFile, flagFrom, flagTo, flagEnv)
+		}
+
+		if _, err := delimiters(ctx); err != nil {
+			return validationError(ctx, "%v", err)
+		}
+
+		if ctx.IsSet(flagEnvFile) && !fs.IsFile(ctx.String(flagEnvFile)) {
+			return validationError(ctx, `file "%s" does not exist`, ctx.String(flagEnvFile))
+		}
+
+		if ctx.IsSet(flagEnvAllow) && !envMode {
+			return validationError(ctx, "--%s can only be used with --%s or --%s", flagEnvAllow, flagEnv, flagEnvFile)
+		}
+
+		if _, err := patterns.Env(nil, ctx.StringSlice(flagEnvAllow)); err != nil {
+			return validationError(ctx, "%v", err)
+		}
+
+		if _, err := replacerOptions(ctx); err != nil {
+			return validationError(ctx, "%v", err)
+		}
+
+		if _, err := walkOptions(ctx); err != nil {
+			return validationError(ctx, "%v", err)
+		}
+
+		if (ctx.IsSet(flagBackup) || ctx.IsSet(flagBackupDir)) && !ctx.Bool(flagInPlace) {
+			return validationError(ctx, "--%s and --%s can only be used with --%s", flagBackup, flagBackupDir, flagInPlace)
+		}
+
+		if ctx.Bool(flagDiff) && !ctx.Bool(flagDryRun) {
+			return validationError(ctx, "--%s can only be used with --%s", flagDiff, flagDryRun)
+		}
+
+		if ctx.Int(flagContext) < 0 {
+			return validationError(ctx, "--%s cannot be negative", flagContext)
+		}
+
+		if ctx.Bool(flagInteractive) && ctx.Bool(flagDryRun) {
+			return validationError(ctx, "--%s cannot be used with --%s", flagInteractive, flagDryRun)
+		}
+
+		if ctx.IsSet(flagOutputDir) && (ctx.Bool(flagInPlace) || ctx.Bool(flagInteractive)) {
+			return validationError(ctx, "--%s cannot be used with --%s or --%s", flagOutputDir, flagInPlace, flagInteractive)
+		}
+
+		if ctx.IsSet(flagOutputDir) && fs.IsFile(ctx.String(flagOutputDir)) {
+			return validationError(ctx, `output directory "%s" is a file`, ctx.String(flagOutputDir))
+		}
+
+		readsStdin := false
+		for _, path := range inputPaths(ctx) {
+			if path == stdinPath {
+				readsStdin = true
+			} else if !fs.IsFile(path) && !fs.IsDir(path) {
+				return validationError(ctx, `file or directory "%s" does not exist`, path)
 			}
 		}
 
-		if ctx.NArg() == 0 {
-			ctx.App.Metadata[metadataValidationErrorsKey] = true
-			return cli.Exit(
-				fmt.Sprintf("%s: reading input from stdin is not supported yet", AppName),
-				ExitCodeValidationError,
-			)
+		if readsStdin {
+			switch {
+			case ctx.IsSet(flagOutputDir):
+				return validationError(ctx, "stdin cannot be written to --%s", flagOutputDir)
+			case ctx.Bool(flagInteractive):
+				return validationError(ctx, "stdin cannot be used with --%s, as answers are read from it", flagInteractive)
+			case ctx.Bool(flagInPlace):
+				return validationError(ctx, "stdin cannot be edited in place")
+			}
 		}
 
 		return nil
 	}
 }
 
+// validationError marks the run as failing validation
+// and returns the error exiting with ExitCodeValidationError
+func validationError(ctx *cli.Context, format string, args ...interface{}) error {
+	ctx.App.Metadata[metadataValidationErrorsKey] = true
+	return cli.Exit(fmt.Sprintf("%s: %s", AppName, fmt.Sprintf(format, args...)), ExitCodeValidationError)
+}
+
+// withDefaultBackupSuffix allows --backup to be given without a suffix,
+// by setting the default suffix to it.
+func withDefaultBackupSuffix(args []string) []string {
+	result := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			return append(result, args[i:]...)
+		}
+
+		if arg == "--"+flagBackup {
+			arg = arg + "=" + defaultBackupSuffix
+		}
+		result = append(result, arg)
+	}
+
+	return result
+}
+
 func overrideDefaultPrinter(defaultPrinter func(w io.Writer, templ string, data interface{})) func(w io.Writer, templ string, data interface{}) {
 	return func(w io.Writer, templ string, data interface{}) {
 		app, ok := data.(*cli.App)
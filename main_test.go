@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aswinkarthik/replace-text/fs"
+	"github.com/aswinkarthik/replace-text/glob"
+	"github.com/aswinkarthik/replace-text/replacer"
+	"github.com/aswinkarthik/replace-text/walk"
+	"github.com/stretchr/testify/assert"
+)
+
+// withStdio replaces stdin and stdout till the returned function is called
+func withStdio(in io.Reader, out io.Writer) func() {
+	oldStdin, oldStdout := stdin, stdout
+	stdin, stdout = in, out
+
+	return func() {
+		stdin, stdout = oldStdin, oldStdout
+	}
+}
+
+func TestReplaceFile(t *testing.T) {
+	t.Run("should replace input read from stdin", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		defer withStdio(strings.NewReader("key1 and key2"), out)()
+
+		r, err := replacer.NewReplacer(map[string]string{"key1": "value1"})
+		assert.NoError(t, err)
+
+		assert.NoError(t, replaceFile(fs.NewMemFs(), r, stdinPath))
+		assert.Equal(t, "value1 and key2", out.String())
+	})
+
+	t.Run("should copy files without replacements as is", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		defer withStdio(strings.NewReader(""), out)()
+
+		memFs := fs.NewMemFs()
+		writeFile(t, memFs, "file.txt", "key2", 0644)
+
+		r, err := replacer.NewReplacer(map[string]string{"key1": "value1"})
+		assert.NoError(t, err)
+
+		assert.NoError(t, replaceFile(memFs, r, "file.txt"))
+		assert.Equal(t, "key2", out.String())
+	})
+}
+
+func TestCountFiles(t *testing.T) {
+	t.Run("should count stdin and keep it for replacing", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		defer withStdio(strings.NewReader("a a"), out)()
+
+		memFs := fs.NewMemFs()
+		writeFile(t, memFs, "file.txt", "a a", 0644)
+
+		r, err := replacer.NewReplacer(map[string]string{"a": "X"},
+			replacer.WithLimit(replacer.Limit{Last: true}),
+			replacer.WithLimitScope(replacer.PerRun),
+		)
+		assert.NoError(t, err)
+
+		inputFiles := []string{"file.txt", stdinPath}
+		assert.NoError(t, countFiles(memFs, r, inputFiles))
+		for _, inputFile := range inputFiles {
+			assert.NoError(t, replaceFile(memFs, r, inputFile))
+		}
+
+		assert.Equal(t, "a aa X", out.String())
+	})
+}
+
+func TestExpandInputPaths(t *testing.T) {
+	t.Run("should replace directories with the files walked in them", func(t *testing.T) {
+		memFs := fs.NewMemFs()
+		assert.NoError(t, memFs.MkdirAll("src/vendor", 0755))
+		writeFile(t, memFs, "src/main.go", "", 0644)
+		writeFile(t, memFs, "src/README.md", "", 0644)
+		writeFile(t, memFs, "src/vendor/lib.go", "", 0644)
+		writeFile(t, memFs, "file.txt", "", 0644)
+
+		files, err := expandInputPaths(memFs, []string{"file.txt", "src", stdinPath}, walk.Options{
+			Include: []*glob.Glob{glob.MustCompile("*.go")},
+			Exclude: []*glob.Glob{glob.MustCompile("vendor/**")},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"file.txt", "src/main.go", stdinPath}, files)
+	})
+}
+
+func TestPatternsFiles(t *testing.T) {
+	memFs := fs.NewMemFs()
+	assert.NoError(t, memFs.MkdirAll("patterns.d/nested", 0755))
+	assert.NoError(t, memFs.MkdirAll("empty", 0755))
+	writeFile(t, memFs, "org.json", "{}", 0644)
+	writeFile(t, memFs, "patterns.d/20-team.yaml", "", 0644)
+	writeFile(t, memFs, "patterns.d/10-base.json", "{}", 0644)
+	writeFile(t, memFs, "patterns.d/README.md", "", 0644)
+	writeFile(t, memFs, "patterns.d/.hidden.json", "{}", 0644)
+	writeFile(t, memFs, "patterns.d/nested/30-other.json", "{}", 0644)
+
+	t.Run("should replace directories with the patterns files in them", func(t *testing.T) {
+		files, err := patternsFiles(memFs, []string{"org.json", "patterns.d"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"org.json", "patterns.d/10-base.json", "patterns.d/20-team.yaml"}, files)
+	})
+
+	t.Run("should reject directories without patterns files", func(t *testing.T) {
+		_, err := patternsFiles(memFs, []string{"empty"})
+		assert.EqualError(t, err, `directory "empty" has no patterns files`)
+	})
+}
+
+func TestDryRun(t *testing.T) {
+	memFs := fs.NewMemFs()
+	writeFile(t, memFs, "changed.txt", "a\nkey\n", 0644)
+	writeFile(t, memFs, "unchanged.txt", "a\n", 0644)
+
+	r, err := replacer.NewReplacer(map[string]string{"key": "value"})
+	assert.NoError(t, err)
+
+	t.Run("should print paths of files that would change", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		defer withStdio(strings.NewReader(""), out)()
+
+		for _, path := range []string{"changed.txt", "unchanged.txt"} {
+			assert.NoError(t, dryRun(false, 3)(memFs, r, path))
+		}
+		assert.Equal(t, "changed.txt\n", out.String())
+		assert.Equal(t, "a\nkey\n", readFile(t, memFs, "changed.txt"))
+	})
+
+	t.Run("should print a unified diff of files that would change", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		defer withStdio(strings.NewReader(""), out)()
+
+		for _, path := range []string{"changed.txt", "unchanged.txt"} {
+			assert.NoError(t, dryRun(true, 0)(memFs, r, path))
+		}
+		assert.Equal(t, "--- a/changed.txt\n+++ b/changed.txt\n@@ -2 +2 @@\n-key\n+value\n", out.String())
+		assert.Equal(t, "a\nkey\n", readFile(t, memFs, "changed.txt"))
+	})
+	t.Run("should name stdin and paths outside of the working directory without prefixes", func(t *testing.T) {
+		wd, err := os.Getwd()
+		assert.NoError(t, err)
+
+		tests := map[string][2]string{
+			"dir/../file.txt":                    {"a/file.txt", "b/file.txt"},
+			filepath.Join(wd, "dir", "file.txt"): {"a/dir/file.txt", "b/dir/file.txt"},
+			"../file.txt":                        {"../file.txt", "../file.txt"},
+			filepath.Join(wd, "..", "file.txt"):  {"../file.txt", "../file.txt"},
+			stdinPath:                            {"<stdin>", "<stdin>"},
+		}
+		for path, expected := range tests {
+			from, to := diffNames(path)
+			assert.Equal(t, expected, [2]string{from, to}, path)
+		}
+	})
+}
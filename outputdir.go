@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/aswinkarthik/replace-text/fs"
+	"github.com/aswinkarthik/replace-text/replacer"
+)
+
+// replaceToDir returns a function writing the replaced content of a file
+// to the same path under dir, with the same file mode.
+// Files without matches are copied as they are.
+func replaceToDir(dir string) func(fs fs.Fs, r *replacer.Replacer, path string) error {
+	return func(fs fs.Fs, r *replacer.Replacer, path string) error {
+		target := mirrorPath(dir, path)
+		if target == filepath.Clean(path) {
+			return fmt.Errorf("error writing %s: output would overwrite the input", path)
+		}
+
+		mode, err := fs.FileMode(path)
+		if err != nil {
+			return fmt.Errorf("error reading file mode of %s: %v", path, err)
+		}
+
+		file, err := fs.Open(path)
+		if err != nil {
+			return fmt.Errorf("error opening input file: %v", err)
+		}
+		defer file.Close()
+
+		if err := fs.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("error creating output directory: %v", err)
+		}
+
+		// An existing output is replaced only once the new one is complete
+		temp, err := fs.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".*", mode&preservedMode)
+		if err != nil {
+			return fmt.Errorf("error creating temporary file for %s: %v", target, err)
+		}
+
+		replaceErr := r.ReplaceFile(path, file, temp)
+		if replaceErr == replacer.ErrNoMatchesFound {
+			replaceErr = nil
+		}
+		if replaceErr == nil {
+			replaceErr = temp.Sync()
+		}
+		if err := temp.Close(); err != nil && replaceErr == nil {
+			replaceErr = err
+		}
+
+		if replaceErr != nil {
+			_ = fs.Remove(temp.Name())
+			return fmt.Errorf("error finding and replacing content in input file: %v", replaceErr)
+		}
+
+		if err := fs.Rename(temp.Name(), target); err != nil {
+			_ = fs.Remove(temp.Name())
+			return fmt.Errorf("error writing %s: %v", target, err)
+		}
+
+		return nil
+	}
+}
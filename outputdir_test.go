@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/aswinkarthik/replace-text/fs"
+	"github.com/aswinkarthik/replace-text/replacer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplaceToDir(t *testing.T) {
+	r, err := replacer.NewReplacer(map[string]string{"key1": "value1"})
+	assert.NoError(t, err)
+
+	t.Run("should write files under the directory preserving their mode", func(t *testing.T) {
+		memFs := fs.NewMemFs()
+		assert.NoError(t, memFs.MkdirAll("tpl/sub", 0755))
+		writeFile(t, memFs, "tpl/sub/file.txt", "key1 and key2", 0751|os.ModeSetgid)
+
+		assert.NoError(t, replaceToDir("out")(memFs, r, "tpl/sub/file.txt"))
+
+		assert.Equal(t, "value1 and key2", readFile(t, memFs, "out/tpl/sub/file.txt"))
+		assert.Equal(t, "key1 and key2", readFile(t, memFs, "tpl/sub/file.txt"))
+		mode, err := memFs.FileMode("out/tpl/sub/file.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, 0751|os.ModeSetgid, mode)
+	})
+
+	t.Run("should copy files without matches and replace existing outputs", func(t *testing.T) {
+		memFs := fs.NewMemFs()
+		assert.NoError(t, memFs.MkdirAll("out", 0755))
+		writeFile(t, memFs, "file.txt", "key2", 0644)
+		writeFile(t, memFs, "out/file.txt", "old", 0644)
+
+		assert.NoError(t, replaceToDir("out")(memFs, r, "file.txt"))
+
+		assert.Equal(t, "key2", readFile(t, memFs, "out/file.txt"))
+		assert.False(t, memFs.IsFile("out/.file.txt.1"))
+	})
+
+	t.Run("should return error if the output is the input", func(t *testing.T) {
+		memFs := fs.NewMemFs()
+		writeFile(t, memFs, "file.txt", "key1", 0644)
+
+		assert.Error(t, replaceToDir(".")(memFs, r, "file.txt"))
+		assert.Equal(t, "key1", readFile(t, memFs, "file.txt"))
+	})
+}
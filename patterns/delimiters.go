@@ -0,0 +1,60 @@
+package patterns
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aswinkarthik/replace-text/replacer"
+)
+
+// spaces matches the spaces and tabs allowed between delimiters and find texts
+const spaces = `[ \t]*`
+
+// Delimiters wrap the find texts of patterns, like the {{ and }}
+// around the keys of templates.
+type Delimiters struct {
+	Prefix string
+	Suffix string
+	// Spaces allows spaces and tabs between the delimiters and the find text,
+	// matching {{ key }} as well as {{key}}
+	Spaces bool
+}
+
+// ParseDelimiters parses the prefix and the suffix separated by a comma,
+// as in "{{,}}". The prefix cannot contain a comma.
+func ParseDelimiters(s string) (Delimiters, error) {
+	i := strings.IndexByte(s, ',')
+	if i < 0 {
+		return Delimiters{}, fmt.Errorf("error parsing delimiters %q: expected prefix,suffix", s)
+	}
+
+	return Delimiters{Prefix: s[:i], Suffix: s[i+1:]}, nil
+}
+
+// Wrap returns the patterns with their find texts between the delimiters.
+// Spaces are matched inside the delimiters of regular expressions, while
+// literal find texts match them only if the replacer is created with
+// replacer.WithDelimiterSpaces, so that they stay on the trie.
+func (d Delimiters) Wrap(ps []replacer.Pattern) []replacer.Pattern {
+	if d.Prefix == "" && d.Suffix == "" {
+		return ps
+	}
+
+	prefix, suffix := regexp.QuoteMeta(d.Prefix), regexp.QuoteMeta(d.Suffix)
+	if d.Spaces {
+		prefix, suffix = prefix+spaces, spaces+suffix
+	}
+
+	result := make([]replacer.Pattern, 0, len(ps))
+	for _, p := range ps {
+		if p.Regexp {
+			p.Find = prefix + "(?:" + p.Find + ")" + suffix
+		} else {
+			p.Find = d.Prefix + p.Find + d.Suffix
+		}
+		result = append(result, p)
+	}
+
+	return result
+}
@@ -0,0 +1,66 @@
+package patterns_test
+
+import (
+	"testing"
+
+	"github.com/aswinkarthik/replace-text/patterns"
+	"github.com/aswinkarthik/replace-text/replacer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDelimiters(t *testing.T) {
+	d, err := patterns.ParseDelimiters("{{,}}")
+	assert.NoError(t, err)
+	assert.Equal(t, patterns.Delimiters{Prefix: "{{", Suffix: "}}"}, d)
+
+	d, err = patterns.ParseDelimiters("@@,@,@")
+	assert.NoError(t, err)
+	assert.Equal(t, patterns.Delimiters{Prefix: "@@", Suffix: "@,@"}, d)
+
+	_, err = patterns.ParseDelimiters("{{")
+	assert.EqualError(t, err, `error parsing delimiters "{{": expected prefix,suffix`)
+}
+
+func TestDelimiters_Wrap(t *testing.T) {
+	ps := []replacer.Pattern{
+		{Find: "name", Replace: "$world"},
+		{Find: "v[0-9]+|rev", Replace: "version", Regexp: true},
+	}
+
+	replace := func(t *testing.T, d patterns.Delimiters, input string) string {
+		var opts []replacer.Option
+		if d.Spaces {
+			opts = append(opts, replacer.WithDelimiterSpaces(d.Prefix, d.Suffix))
+		}
+
+		r, err := replacer.NewPatternReplacer(d.Wrap(ps), opts...)
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString(input)
+		assert.NoError(t, err)
+		return output
+	}
+
+	t.Run("should match find texts between the delimiters", func(t *testing.T) {
+		d := patterns.Delimiters{Prefix: "{{", Suffix: "}}"}
+		output := replace(t, d, "hello {{name}}, name {{ name }} {{v2}} {{rev}} rev")
+		assert.Equal(t, "hello $world, name {{ name }} version version rev", output)
+	})
+
+	t.Run("should allow spaces between the delimiters and find texts", func(t *testing.T) {
+		d := patterns.Delimiters{Prefix: "@@", Suffix: "@@", Spaces: true}
+		output := replace(t, d, "@@name@@ @@ name\t@@ @@ v2 @@ @@na me@@")
+		assert.Equal(t, "$world $world version @@na me@@", output)
+	})
+
+	t.Run("should keep patterns without delimiters", func(t *testing.T) {
+		output := replace(t, patterns.Delimiters{Spaces: true}, "name v1")
+		assert.Equal(t, "$world version", output)
+	})
+
+	t.Run("should keep literal find texts on the trie when spaces are allowed", func(t *testing.T) {
+		d := patterns.Delimiters{Prefix: "{{", Suffix: "}}", Spaces: true}
+		wrapped := d.Wrap([]replacer.Pattern{{Find: "a\nb", Replace: "c"}})
+		assert.Equal(t, []replacer.Pattern{{Find: "{{a\nb}}", Replace: "c"}}, wrapped)
+	})
+}
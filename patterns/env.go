@@ -0,0 +1,159 @@
+package patterns
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aswinkarthik/replace-text/replacer"
+)
+
+// variableName matches names of variables that can be substituted
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// anyVariable matches the name of any variable in a placeholder
+const anyVariable = `[A-Za-z_][A-Za-z0-9_]*`
+
+// defaultMessage is the error of ${VAR:?} without a message, as in shells
+const defaultMessage = "parameter null or not set"
+
+// lookup is the value of the variable named by the first submatch in templates
+const lookup = `index .Env (index .Groups 1)`
+
+// Env returns patterns substituting variables of env in placeholders,
+// as done by shells and envsubst.
+//
+//	${VAR} and $VAR       the value of VAR
+//	${VAR:-default}       the value of VAR, or default if VAR is unset or empty
+//	${VAR:?error}         the value of VAR, failing with error if VAR is unset or empty
+//
+// Placeholders of variables that are not in env are kept as they are,
+// unless they have a default or an error. If allowed is not empty,
+// placeholders of only the variables named in it are substituted.
+// Placeholders do not span lines, and defaults and errors cannot contain '}'.
+//
+// ${VAR} placeholders are literal patterns, while each of the other forms is
+// a single templated regular expression looking the variable up in .Env,
+// so the replacer must be created with replacer.WithEnv(env).
+func Env(env map[string]string, allowed []string) ([]replacer.Pattern, error) {
+	isAllowed := make(map[string]bool, len(allowed))
+	for _, name := range allowed {
+		if !variableName.MatchString(name) {
+			return nil, fmt.Errorf("invalid variable name %q", name)
+		}
+		isAllowed[name] = true
+	}
+
+	names := make([]string, 0, len(env))
+	for name := range env {
+		if variableName.MatchString(name) && (len(allowed) == 0 || isAllowed[name]) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var result []replacer.Pattern
+	for _, name := range names {
+		result = append(result, replacer.Pattern{Find: "${" + name + "}", Replace: env[name]})
+	}
+
+	if len(names) > 0 {
+		result = append(result, replacer.Pattern{
+			Find:     `\$(` + strings.Join(names, "|") + `)\b`,
+			Replace:  "{{" + lookup + "}}",
+			Regexp:   true,
+			Template: true,
+		})
+	}
+
+	anyName := anyVariable
+	if len(allowed) > 0 {
+		anyName = strings.Join(allowed, "|")
+	}
+
+	return append(result,
+		replacer.Pattern{
+			Find:     `\$\{(` + anyName + `):-([^}\n]*)\}`,
+			Replace:  `{{with ` + lookup + `}}{{.}}{{else}}{{index .Groups 2}}{{end}}`,
+			Regexp:   true,
+			Template: true,
+		},
+		replacer.Pattern{
+			Find:     `\$\{(` + anyName + `):\?([^}\n]*)\}`,
+			Replace:  `{{with ` + lookup + `}}{{.}}{{else}}{{fail (printf "%s: %s" (index .Groups 1) (or (index .Groups 2) "` + defaultMessage + `"))}}{{end}}`,
+			Regexp:   true,
+			Template: true,
+		},
+	), nil
+}
+
+// ParseEnvFile reads variables from a .env file. Every line is a NAME=value
+// assignment, optionally preceded by export. Blank lines and lines starting
+// with # are skipped. Values can be wrapped in single quotes to keep them
+// as they are, or in double quotes to expand escapes like \n. Unquoted values
+// are trimmed, and end at a # preceded by a space.
+func ParseEnvFile(reader io.Reader) (map[string]string, error) {
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(reader)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		i := strings.IndexByte(line, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("error parsing env file: line %d: expected NAME=value", number)
+		}
+
+		name := strings.TrimSpace(line[:i])
+		if !variableName.MatchString(name) {
+			return nil, fmt.Errorf("error parsing env file: line %d: invalid variable name %q", number, name)
+		}
+
+		value, err := envValue(strings.TrimSpace(line[i+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing env file: line %d: %v", number, err)
+		}
+		env[name] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading env file: %v", err)
+	}
+
+	return env, nil
+}
+
+// envValue returns the value of an assignment in a .env file
+func envValue(raw string) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+
+	switch quote := raw[0]; quote {
+	case '\'', '"':
+		end := strings.LastIndexByte(raw, quote)
+		if end == 0 {
+			return "", fmt.Errorf("unterminated quote in %s", raw)
+		}
+		if rest := strings.TrimSpace(raw[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", fmt.Errorf("unexpected %q after quoted value", rest)
+		}
+		if quote == '\'' {
+			return raw[1:end], nil
+		}
+		return strconv.Unquote(raw[:end+1])
+	}
+
+	if i := strings.Index(raw, " #"); i >= 0 {
+		raw = raw[:i]
+	}
+
+	return strings.TrimSpace(raw), nil
+}
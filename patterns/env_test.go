@@ -0,0 +1,108 @@
+package patterns_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aswinkarthik/replace-text/patterns"
+	"github.com/aswinkarthik/replace-text/replacer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnv(t *testing.T) {
+	env := map[string]string{"HOST": "example.com", "PORT": "8080", "EMPTY": "", "PRICE": "$5"}
+
+	substitute := func(t *testing.T, allowed []string, input string) (string, error) {
+		p, err := patterns.Env(env, allowed)
+		assert.NoError(t, err)
+
+		r, err := replacer.NewPatternReplacer(p, replacer.WithEnv(env))
+		assert.NoError(t, err)
+
+		return r.ReplaceString(input)
+	}
+
+	t.Run("should substitute variables", func(t *testing.T) {
+		output, err := substitute(t, nil, "http://${HOST}:$PORT/ $HOSTNAME $PRICE ${MISSING} $MISSING")
+		assert.NoError(t, err)
+		assert.Equal(t, "http://example.com:8080/ $HOSTNAME $5 ${MISSING} $MISSING", output)
+	})
+
+	t.Run("should substitute defaults of unset or empty variables", func(t *testing.T) {
+		output, err := substitute(t, nil, "${HOST:-localhost} ${MISSING:-localhost} ${EMPTY:-none} ${PRICE:-free}")
+		assert.NoError(t, err)
+		assert.Equal(t, "example.com localhost none $5", output)
+	})
+
+	t.Run("should fail for unset or empty variables with an error", func(t *testing.T) {
+		output, err := substitute(t, nil, "${PORT:?port is required}")
+		assert.NoError(t, err)
+		assert.Equal(t, "8080", output)
+
+		_, err = substitute(t, nil, "${MISSING:?is required}")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "MISSING: is required")
+
+		_, err = substitute(t, nil, "${EMPTY:?}")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "EMPTY: parameter null or not set")
+	})
+
+	t.Run("should substitute only allowed variables", func(t *testing.T) {
+		output, err := substitute(t, []string{"HOST", "MISSING"}, "${HOST} ${PORT} ${MISSING:-a} ${OTHER:-b} ${OTHER:?c}")
+		assert.NoError(t, err)
+		assert.Equal(t, "example.com ${PORT} a ${OTHER:-b} ${OTHER:?c}", output)
+	})
+
+	t.Run("should use one regular expression per form of placeholders", func(t *testing.T) {
+		p, err := patterns.Env(env, nil)
+		assert.NoError(t, err)
+
+		regexps := 0
+		for _, pattern := range p {
+			if pattern.Regexp {
+				regexps++
+			}
+		}
+		assert.Equal(t, 3, regexps)
+		assert.Len(t, p, len(env)+3)
+	})
+
+	t.Run("should reject invalid names in the allow-list", func(t *testing.T) {
+		_, err := patterns.Env(env, []string{"HOST", "1PORT"})
+		assert.EqualError(t, err, `invalid variable name "1PORT"`)
+	})
+}
+
+func TestParseEnvFile(t *testing.T) {
+	t.Run("should read assignments", func(t *testing.T) {
+		input := `
+# database
+export DB_HOST=localhost
+DB_PORT = 5432 # default port
+DB_NAME='app #1'
+DB_PASS="multi\nline"
+EMPTY=
+`
+		env, err := patterns.ParseEnvFile(strings.NewReader(input))
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"DB_HOST": "localhost",
+			"DB_PORT": "5432",
+			"DB_NAME": "app #1",
+			"DB_PASS": "multi\nline",
+			"EMPTY":   "",
+		}, env)
+	})
+
+	t.Run("should reject invalid lines", func(t *testing.T) {
+		_, err := patterns.ParseEnvFile(strings.NewReader("A=1\nB\n"))
+		assert.EqualError(t, err, "error parsing env file: line 2: expected NAME=value")
+
+		_, err = patterns.ParseEnvFile(strings.NewReader("A-B=1\n"))
+		assert.EqualError(t, err, `error parsing env file: line 1: invalid variable name "A-B"`)
+
+		_, err = patterns.ParseEnvFile(strings.NewReader("A='1\n"))
+		assert.EqualError(t, err, "error parsing env file: line 1: unterminated quote in '1")
+	})
+}
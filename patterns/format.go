@@ -0,0 +1,252 @@
+package patterns
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Format is the format of a patterns file
+type Format int
+
+const (
+	// JSON patterns files hold a JSON object
+	JSON Format = iota
+	// YAML patterns files hold a YAML mapping
+	YAML
+	// TOML patterns files hold a TOML table
+	TOML
+	// CSV patterns files hold rows of comma separated values
+	CSV
+	// TSV patterns files hold rows of tab separated values
+	TSV
+)
+
+var formatNames = map[Format]string{
+	JSON: "json",
+	YAML: "yaml",
+	TOML: "toml",
+	CSV:  "csv",
+	TSV:  "tsv",
+}
+
+func (f Format) String() string {
+	return formatNames[f]
+}
+
+// ParseFormat returns the Format of the given name
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "json":
+		return JSON, nil
+	case "yaml", "yml":
+		return YAML, nil
+	case "toml":
+		return TOML, nil
+	case "csv":
+		return CSV, nil
+	case "tsv", "tab":
+		return TSV, nil
+	}
+
+	return JSON, fmt.Errorf("unknown patterns format %q", name)
+}
+
+// FormatOf detects the format of a patterns file by the extension of path.
+// Files with any other extension are JSON.
+func FormatOf(path string) Format {
+	format, err := ParseFormat(strings.TrimPrefix(filepath.Ext(path), "."))
+	if err != nil {
+		return JSON
+	}
+
+	return format
+}
+
+// decodeDocument reads a patterns file of the format,
+// and returns its JSON encoding.
+func decodeDocument(reader io.Reader, format Format) (json.RawMessage, error) {
+	var value interface{}
+	switch format {
+	case JSON:
+		var raw json.RawMessage
+		if err := json.NewDecoder(reader).Decode(&raw); err != nil {
+			return nil, err
+		}
+		return raw, nil
+	case YAML:
+		var root yamlValue
+		if err := yaml.NewDecoder(reader).Decode(&root); err != nil && err != io.EOF {
+			return nil, err
+		}
+		value = root.value
+		if value == nil {
+			value = map[string]interface{}{}
+		}
+	case TOML:
+		var table map[string]interface{}
+		if _, err := toml.DecodeReader(reader, &table); err != nil {
+			return nil, err
+		}
+		value = table
+	default:
+		return nil, fmt.Errorf("unknown patterns format %d", format)
+	}
+
+	value, err := textual("", value, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(value)
+}
+
+// withoutBOM skips a byte order mark at the start of reader.
+// UTF-16 text, which is recognized by its byte order mark, is converted to UTF-8.
+func withoutBOM(reader io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(reader)
+	start, _ := buffered.Peek(3)
+
+	var order binary.ByteOrder
+	switch {
+	case bytes.HasPrefix(start, []byte{0xEF, 0xBB, 0xBF}):
+		_, err := buffered.Discard(3)
+		return buffered, err
+	case bytes.HasPrefix(start, []byte{0xFF, 0xFE}):
+		order = binary.LittleEndian
+	case bytes.HasPrefix(start, []byte{0xFE, 0xFF}):
+		order = binary.BigEndian
+	default:
+		return buffered, nil
+	}
+
+	data, err := ioutil.ReadAll(buffered)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%2 != 0 {
+		return nil, fmt.Errorf("UTF-16 text has an odd number of bytes")
+	}
+
+	units := make([]uint16, 0, len(data)/2-1)
+	for i := 2; i < len(data); i += 2 {
+		units = append(units, order.Uint16(data[i:]))
+	}
+
+	return strings.NewReader(string(utf16.Decode(units))), nil
+}
+
+// scalar is a YAML number, boolean or timestamp along with its source text
+type scalar struct {
+	typed interface{}
+	text  string
+}
+
+// yamlValue decodes a YAML value keeping the source text of its scalars,
+// so that 1.10 is not read as 1.1, or 0755 as 493.
+// Maps are decoded with string keys, which can be encoded as JSON.
+type yamlValue struct {
+	value interface{}
+}
+
+// UnmarshalYAML is implemented to conform to yaml.Unmarshaler interface.
+func (y *yamlValue) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var typed interface{}
+	if err := unmarshal(&typed); err != nil {
+		return err
+	}
+
+	switch typed.(type) {
+	case map[interface{}]interface{}:
+		var values map[string]yamlValue
+		if err := unmarshal(&values); err != nil {
+			return err
+		}
+		m := make(map[string]interface{}, len(values))
+		for k, v := range values {
+			m[k] = v.value
+		}
+		y.value = m
+	case []interface{}:
+		var values []yamlValue
+		if err := unmarshal(&values); err != nil {
+			return err
+		}
+		s := make([]interface{}, len(values))
+		for i, v := range values {
+			s[i] = v.value
+		}
+		y.value = s
+	case nil, string:
+		y.value = typed
+	default:
+		var text string
+		if err := unmarshal(&text); err != nil {
+			return err
+		}
+		y.value = scalar{typed: typed, text: text}
+	}
+
+	return nil
+}
+
+// textual replaces scalars with their source text, except for the options
+// of patterns written as objects, which are kept typed if typed is set.
+// Values of TOML files have no source text, so numbers and booleans
+// are rejected unless they are options. key is where v is in the file.
+func textual(key string, v interface{}, typed bool) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		_, isEntry := v["replace"]
+		result := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			isOption := isEntry && k != "find" && k != "replace" && k != "comment"
+			var err error
+			if result[k], err = textual(strings.TrimPrefix(key+"."+k, "."), value, isOption); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	case []map[string]interface{}:
+		result := make([]interface{}, len(v))
+		for i, value := range v {
+			var err error
+			if result[i], err = textual(fmt.Sprintf("%s[%d]", key, i), value, false); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, value := range v {
+			var err error
+			if result[i], err = textual(fmt.Sprintf("%s[%d]", key, i), value, false); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	case scalar:
+		if typed {
+			return v.typed, nil
+		}
+		return v.text, nil
+	case nil, string:
+		return v, nil
+	}
+
+	if typed {
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("value %v of %q must be quoted as a string to keep its text", v, key)
+}
@@ -0,0 +1,155 @@
+package patterns_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aswinkarthik/replace-text/patterns"
+	"github.com/aswinkarthik/replace-text/replacer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatOf(t *testing.T) {
+	assert.Equal(t, patterns.YAML, patterns.FormatOf("patterns.yaml"))
+	assert.Equal(t, patterns.YAML, patterns.FormatOf("patterns.YML"))
+	assert.Equal(t, patterns.TOML, patterns.FormatOf("dir/patterns.toml"))
+	assert.Equal(t, patterns.JSON, patterns.FormatOf("patterns.json"))
+	assert.Equal(t, patterns.JSON, patterns.FormatOf("patterns"))
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := patterns.ParseFormat("toml")
+	assert.NoError(t, err)
+	assert.Equal(t, patterns.TOML, format)
+
+	_, err = patterns.ParseFormat("xml")
+	assert.Error(t, err)
+}
+
+func TestDecode_Formats(t *testing.T) {
+	expected := []replacer.Pattern{
+		{Find: "id", Replace: "identifier", WholeWord: true},
+		{Find: "key1", Replace: "value1"},
+		{Find: "port", Replace: "8080"},
+		{Find: "v[0-9]+", Replace: "version", Regexp: true},
+	}
+
+	t.Run("should decode YAML", func(t *testing.T) {
+		input := `
+key1: value1
+port: 8080
+"re:v[0-9]+": version
+id:
+  replace: identifier
+  wholeWord: true
+`
+		result, err := patterns.Decode(strings.NewReader(input), patterns.WithFormat(patterns.YAML))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, result)
+	})
+
+	t.Run("should decode TOML", func(t *testing.T) {
+		input := `
+key1 = "value1"
+port = "8080"
+"re:v[0-9]+" = "version"
+id = { replace = "identifier", wholeWord = true }
+`
+		result, err := patterns.Decode(strings.NewReader(input), patterns.WithFormat(patterns.TOML))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, result)
+	})
+
+	t.Run("should keep the text of YAML numbers", func(t *testing.T) {
+		input := `
+VER: 1.10
+OCT: 0755
+HEX: 0x1F
+EXP: 1e3
+ON: yes
+1.20: v
+id: { replace: 0755, wholeWord: true, maxCount: 2 }
+`
+		result, err := patterns.Decode(strings.NewReader(input), patterns.WithFormat(patterns.YAML))
+		assert.NoError(t, err)
+		assert.Equal(t, []replacer.Pattern{
+			{Find: "1.20", Replace: "v"},
+			{Find: "EXP", Replace: "1e3"},
+			{Find: "HEX", Replace: "0x1F"},
+			{Find: "OCT", Replace: "0755"},
+			{Find: "ON", Replace: "yes"},
+			{Find: "VER", Replace: "1.10"},
+			{Find: "id", Replace: "0755", WholeWord: true, Limit: replacer.Limit{MaxCount: 2}},
+		}, result)
+	})
+
+	t.Run("should reject unquoted TOML numbers", func(t *testing.T) {
+		_, err := patterns.Decode(strings.NewReader("VER = 1.10\n"), patterns.WithFormat(patterns.TOML))
+		assert.EqualError(t, err, `error decoding patterns: value 1.1 of "VER" must be quoted as a string to keep its text`)
+
+		result, err := patterns.Decode(strings.NewReader(`id = { replace = "0755", maxCount = 2 }`), patterns.WithFormat(patterns.TOML))
+		assert.NoError(t, err)
+		assert.Equal(t, []replacer.Pattern{{Find: "id", Replace: "0755", Limit: replacer.Limit{MaxCount: 2}}}, result)
+	})
+
+	t.Run("should decode an empty YAML file", func(t *testing.T) {
+		result, err := patterns.Decode(strings.NewReader(""), patterns.WithFormat(patterns.YAML))
+		assert.NoError(t, err)
+		assert.Empty(t, result)
+	})
+
+	t.Run("should reject unknown options in YAML", func(t *testing.T) {
+		_, err := patterns.Decode(strings.NewReader("id: {replace: x, wholeWords: true}"), patterns.WithFormat(patterns.YAML))
+		assert.EqualError(t, err, `error decoding patterns: "id": json: unknown field "wholeWords"`)
+	})
+}
+
+func TestDecode_Flatten(t *testing.T) {
+	t.Run("should flatten nested maps into dotted keys", func(t *testing.T) {
+		input := `
+db:
+  host: localhost
+  port: 5432
+  name:
+    replace: app
+    wholeWord: true
+app.name: demo
+`
+		result, err := patterns.Decode(strings.NewReader(input), patterns.WithFormat(patterns.YAML), patterns.WithFlatten())
+		assert.NoError(t, err)
+		assert.Equal(t, []replacer.Pattern{
+			{Find: "app.name", Replace: "demo"},
+			{Find: "db.host", Replace: "localhost"},
+			{Find: "db.name", Replace: "app", WholeWord: true},
+			{Find: "db.port", Replace: "5432"},
+		}, result)
+	})
+
+	t.Run("should flatten TOML tables", func(t *testing.T) {
+		input := `
+[db]
+host = "localhost"
+`
+		result, err := patterns.Decode(strings.NewReader(input), patterns.WithFormat(patterns.TOML), patterns.WithFlatten())
+		assert.NoError(t, err)
+		assert.Equal(t, []replacer.Pattern{{Find: "db.host", Replace: "localhost"}}, result)
+	})
+
+	t.Run("should return error if flattened keys collide", func(t *testing.T) {
+		input := `{"a.b": "1", "a": {"b": "2"}}`
+		_, err := patterns.Decode(strings.NewReader(input), patterns.WithFlatten())
+		assert.Error(t, err)
+	})
+
+	t.Run("should reject nested maps if not flattened", func(t *testing.T) {
+		_, err := patterns.Decode(strings.NewReader(`{"db": {"host": "localhost"}}`))
+		assert.Equal(t, &patterns.NestedMapError{Key: "db"}, err)
+		assert.EqualError(t, err, `error decoding patterns: "db" holds a nested map instead of a replacement or options`)
+	})
+
+	t.Run("should decode options without a replacement if not flattened", func(t *testing.T) {
+		result, err := patterns.Decode(strings.NewReader(`{"id": {"wholeWord": true}}`))
+		assert.NoError(t, err)
+		assert.Equal(t, []replacer.Pattern{{Find: "id", WholeWord: true}}, result)
+	})
+}
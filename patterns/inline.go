@@ -0,0 +1,58 @@
+package patterns
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aswinkarthik/replace-text/replacer"
+)
+
+// DefaultSeparator separates the find text from the replacement in an expression
+const DefaultSeparator = "="
+
+// escape makes the character after it literal in an expression
+const escape = '\\'
+
+// ParseExpression parses an inline pattern written as find, separator and replacement.
+// The expression is split at the first separator that is not escaped with a backslash.
+// A backslash before the separator or another backslash is removed,
+// while any other backslash is kept as it is. A find text starting with
+// RegexpPrefix is a regular expression.
+func ParseExpression(expr, separator string) (replacer.Pattern, error) {
+	if separator == "" {
+		return replacer.Pattern{}, fmt.Errorf("error parsing expression %q: separator is empty", expr)
+	}
+
+	for i := 0; i < len(expr); i++ {
+		switch {
+		case expr[i] == escape && strings.HasPrefix(expr[i+1:], separator):
+			i += len(separator)
+		case expr[i] == escape:
+			i++
+		case strings.HasPrefix(expr[i:], separator):
+			entry := Entry{Replace: unescape(expr[i+len(separator):], separator)}
+			return entry.Pattern(unescape(expr[:i], separator)), nil
+		}
+	}
+
+	return replacer.Pattern{}, fmt.Errorf("error parsing expression %q: separator %q not found", expr, separator)
+}
+
+// unescape removes backslashes before the separator or another backslash
+func unescape(s, separator string) string {
+	var result strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == escape && strings.HasPrefix(s[i+1:], separator):
+			result.WriteString(separator)
+			i += len(separator)
+		case s[i] == escape && i+1 < len(s) && s[i+1] == escape:
+			result.WriteByte(escape)
+			i++
+		default:
+			result.WriteByte(s[i])
+		}
+	}
+
+	return result.String()
+}
@@ -0,0 +1,43 @@
+package patterns_test
+
+import (
+	"testing"
+
+	"github.com/aswinkarthik/replace-text/patterns"
+	"github.com/aswinkarthik/replace-text/replacer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseExpression(t *testing.T) {
+	t.Run("should split at the first separator", func(t *testing.T) {
+		p, err := patterns.ParseExpression("old=new=value", "=")
+		assert.NoError(t, err)
+		assert.Equal(t, replacer.Pattern{Find: "old", Replace: "new=value"}, p)
+	})
+
+	t.Run("should unescape separators and backslashes", func(t *testing.T) {
+		p, err := patterns.ParseExpression(`a\=b\\=c\d\=`, "=")
+		assert.NoError(t, err)
+		assert.Equal(t, replacer.Pattern{Find: `a=b\`, Replace: `c\d=`}, p)
+	})
+
+	t.Run("should use the given separator", func(t *testing.T) {
+		p, err := patterns.ParseExpression("a=b -> c\\ -> d", " -> ")
+		assert.NoError(t, err)
+		assert.Equal(t, replacer.Pattern{Find: "a=b", Replace: "c -> d"}, p)
+	})
+
+	t.Run("should parse regular expressions", func(t *testing.T) {
+		p, err := patterns.ParseExpression(`re:v\d+=version`, "=")
+		assert.NoError(t, err)
+		assert.Equal(t, replacer.Pattern{Find: `v\d+`, Replace: "version", Regexp: true}, p)
+	})
+
+	t.Run("should return error if there is no separator", func(t *testing.T) {
+		_, err := patterns.ParseExpression(`old\=new`, "=")
+		assert.Error(t, err)
+
+		_, err = patterns.ParseExpression("old=new", "")
+		assert.Error(t, err)
+	})
+}
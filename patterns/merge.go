@@ -0,0 +1,97 @@
+package patterns
+
+import (
+	"fmt"
+
+	"github.com/aswinkarthik/replace-text/replacer"
+)
+
+// Source is a set of patterns along with the name of where they come from
+type Source struct {
+	Name     string
+	Patterns []replacer.Pattern
+}
+
+// Conflict is a pattern overriding a different pattern with the same find text
+// that comes from an earlier source
+type Conflict struct {
+	Pattern    replacer.Pattern
+	Source     string
+	Overridden replacer.Pattern
+	// OverriddenSource is the name of the source of the overridden pattern
+	OverriddenSource string
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s of %s overrides %s of %s", describe(c.Pattern), c.Source, describe(c.Overridden), c.OverriddenSource)
+}
+
+func describe(p replacer.Pattern) string {
+	find := p.Find
+	if p.Regexp {
+		find = RegexpPrefix + find
+	}
+
+	return fmt.Sprintf("%q -> %q", find, p.Replace)
+}
+
+// Origin is a merged pattern along with the name of the source it comes from
+type Origin struct {
+	Pattern replacer.Pattern
+	Source  string
+}
+
+func (o Origin) String() string {
+	return fmt.Sprintf("%s from %s", describe(o.Pattern), o.Source)
+}
+
+// Merge combines the patterns of the sources. A pattern overrides the pattern
+// with the same find text of an earlier source, and takes its place in the order.
+// Overrides that change the pattern are returned as conflicts.
+func Merge(sources ...Source) ([]replacer.Pattern, []Conflict) {
+	origins, conflicts := MergeOrigins(sources...)
+
+	merged := make([]replacer.Pattern, 0, len(origins))
+	for _, o := range origins {
+		merged = append(merged, o.Pattern)
+	}
+
+	return merged, conflicts
+}
+
+// MergeOrigins combines the patterns of the sources like Merge,
+// keeping the name of the source every merged pattern comes from.
+func MergeOrigins(sources ...Source) ([]Origin, []Conflict) {
+	type key struct {
+		find   string
+		regexp bool
+	}
+
+	var merged []Origin
+	var conflicts []Conflict
+	positions := map[key]int{}
+
+	for _, source := range sources {
+		for _, p := range source.Patterns {
+			k := key{p.Find, p.Regexp}
+			i, exists := positions[k]
+			if !exists {
+				positions[k] = len(merged)
+				merged = append(merged, Origin{Pattern: p, Source: source.Name})
+				continue
+			}
+
+			if merged[i].Pattern != p {
+				conflicts = append(conflicts, Conflict{
+					Pattern:          p,
+					Source:           source.Name,
+					Overridden:       merged[i].Pattern,
+					OverriddenSource: merged[i].Source,
+				})
+			}
+			merged[i] = Origin{Pattern: p, Source: source.Name}
+		}
+	}
+
+	return merged, conflicts
+}
@@ -0,0 +1,55 @@
+package patterns_test
+
+import (
+	"testing"
+
+	"github.com/aswinkarthik/replace-text/patterns"
+	"github.com/aswinkarthik/replace-text/replacer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	t.Run("should override patterns of earlier sources in place", func(t *testing.T) {
+		merged, conflicts := patterns.Merge(
+			patterns.Source{Name: "patterns.json", Patterns: []replacer.Pattern{
+				{Find: "a", Replace: "1"},
+				{Find: "b", Replace: "2"},
+				{Find: "c", Replace: "3", Regexp: true},
+			}},
+			patterns.Source{Name: "-e", Patterns: []replacer.Pattern{
+				{Find: "a", Replace: "one"},
+				{Find: "b", Replace: "2"},
+				{Find: "c", Replace: "three"},
+			}},
+		)
+
+		assert.Equal(t, []replacer.Pattern{
+			{Find: "a", Replace: "one"},
+			{Find: "b", Replace: "2"},
+			{Find: "c", Replace: "3", Regexp: true},
+			{Find: "c", Replace: "three"},
+		}, merged)
+
+		assert.Len(t, conflicts, 1)
+		assert.Equal(t, `"a" -> "one" of -e overrides "a" -> "1" of patterns.json`, conflicts[0].String())
+	})
+
+	t.Run("should keep the source of every merged pattern", func(t *testing.T) {
+		origins, conflicts := patterns.MergeOrigins(
+			patterns.Source{Name: "org.json", Patterns: []replacer.Pattern{
+				{Find: "a", Replace: "1"},
+				{Find: "b", Replace: "2"},
+			}},
+			patterns.Source{Name: "team.json", Patterns: []replacer.Pattern{
+				{Find: "b", Replace: "two"},
+			}},
+		)
+
+		assert.Equal(t, []patterns.Origin{
+			{Pattern: replacer.Pattern{Find: "a", Replace: "1"}, Source: "org.json"},
+			{Pattern: replacer.Pattern{Find: "b", Replace: "two"}, Source: "team.json"},
+		}, origins)
+		assert.Equal(t, `"b" -> "two" from team.json`, origins[1].String())
+		assert.Len(t, conflicts, 1)
+	})
+}
@@ -0,0 +1,242 @@
+// Package patterns reads find & replace patterns from patterns files.
+package patterns
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aswinkarthik/replace-text/replacer"
+)
+
+// RegexpPrefix marks a find text as an RE2 regular expression.
+// The prefix is not part of the expression.
+const RegexpPrefix = "re:"
+
+// Entry is the value of a single pattern in a patterns file.
+// It can be written either as the replacement string,
+// or as an object holding the replacement along with options.
+type Entry struct {
+	Replace         string `json:"replace"`
+	WholeWord       bool   `json:"wholeWord"`
+	Regexp          bool   `json:"regexp"`
+	Template        bool   `json:"template"`
+	CaseInsensitive bool   `json:"caseInsensitive"`
+	MaxCount        int    `json:"maxCount"`
+	Nth             int    `json:"nth"`
+	Last            bool   `json:"last"`
+}
+
+// UnmarshalJSON is implemented to conform to Unmarshaler interface.
+// It accepts a string or an object. Unknown options in the object
+// and negative limits are rejected.
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*e = Entry{}
+		return json.Unmarshal(data, &e.Replace)
+	}
+
+	type entry Entry
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode((*entry)(e)); err != nil {
+		return err
+	}
+
+	if e.MaxCount < 0 {
+		return fmt.Errorf("maxCount cannot be negative")
+	}
+	if e.Nth < 0 {
+		return fmt.Errorf("nth cannot be negative")
+	}
+
+	return nil
+}
+
+// Pattern converts the entry to a replacer pattern for the given find text.
+// A find text starting with RegexpPrefix is a regular expression.
+func (e Entry) Pattern(find string) replacer.Pattern {
+	isRegexp := e.Regexp
+	if strings.HasPrefix(find, RegexpPrefix) {
+		find = strings.TrimPrefix(find, RegexpPrefix)
+		isRegexp = true
+	}
+
+	return replacer.Pattern{
+		Find:       find,
+		Replace:    e.Replace,
+		WholeWord:  e.WholeWord,
+		Regexp:     isRegexp,
+		Template:   e.Template,
+		IgnoreCase: e.CaseInsensitive,
+		Limit: replacer.Limit{
+			MaxCount: e.MaxCount,
+			Nth:      e.Nth,
+			Last:     e.Last,
+		},
+	}
+}
+
+// DecodeOption configures how patterns files are decoded
+type DecodeOption func(*decodeOptions)
+
+type decodeOptions struct {
+	format  Format
+	flatten bool
+	// findColumn and replaceColumn name the columns of CSV and TSV files
+	findColumn    string
+	replaceColumn string
+}
+
+// WithFormat sets the format of the patterns file. It is JSON by default.
+func WithFormat(format Format) DecodeOption {
+	return func(o *decodeOptions) {
+		o.format = format
+	}
+}
+
+// WithFlatten flattens nested maps into patterns with dotted keys.
+// A map with a "replace" key is still the options of a single pattern.
+//
+//	{ "db": { "host": "localhost", "port": { "replace": "5432" } } }
+//
+// is the same as
+//
+//	{ "db.host": "localhost", "db.port": "5432" }
+func WithFlatten() DecodeOption {
+	return func(o *decodeOptions) {
+		o.flatten = true
+	}
+}
+
+// Decode reads an object of find & replace pairs from reader.
+//
+//	{
+//	  "key1": "value1",
+//	  "re:v[0-9]+": "version",
+//	  "id": { "replace": "identifier", "wholeWord": true },
+//	  "[a-z]+id": { "replace": "identifier", "regexp": true },
+//	  "todo": { "replace": "{{.File}}:{{.Line}}", "template": true },
+//	  "version": { "replace": "v2", "maxCount": 1 }
+//	}
+//
+// The object is JSON unless another format is set with WithFormat.
+// Replacements in YAML can also be numbers or booleans, which are replaced
+// with their text as written. In TOML they have to be quoted as strings. A byte order mark at the start
+// of the file is skipped, and UTF-16 files are converted to UTF-8.
+//
+// Patterns are returned ordered by their find text,
+// except for CSV and TSV files where they are in the order of their rows.
+func Decode(reader io.Reader, opts ...DecodeOption) ([]replacer.Pattern, error) {
+	o := decodeOptions{format: JSON}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	reader, err := withoutBOM(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding patterns: %v", err)
+	}
+
+	if o.format == CSV || o.format == TSV {
+		result, err := o.decodeTable(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding patterns: %v", err)
+		}
+		return result, nil
+	}
+
+	document, err := decodeDocument(reader, o.format)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding patterns: %v", err)
+	}
+
+	if trimmed := bytes.TrimSpace(document); len(trimmed) > 0 && trimmed[0] == '[' {
+		result, err := o.decodeRules(document)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding patterns: %v", err)
+		}
+		return result, nil
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(document, &raw); err != nil {
+		return nil, fmt.Errorf("error decoding patterns: %v", err)
+	}
+
+	entries := make(map[string]Entry, len(raw))
+	if err := o.collect(entries, "", raw); err != nil {
+		if nested, ok := err.(*NestedMapError); ok {
+			return nil, nested
+		}
+		return nil, fmt.Errorf("error decoding patterns: %v", err)
+	}
+
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make([]replacer.Pattern, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, entries[k].Pattern(k))
+	}
+
+	return result, nil
+}
+
+// NestedMapError is returned by Decode for a nested map,
+// which is decoded only if flattening is enabled with WithFlatten.
+type NestedMapError struct {
+	// Key is the key holding the nested map
+	Key string
+}
+
+func (e *NestedMapError) Error() string {
+	return fmt.Sprintf("error decoding patterns: %q holds a nested map instead of a replacement or options", e.Key)
+}
+
+// collect decodes the raw values into entries,
+// flattening nested maps if enabled.
+// Objects without a replacement are nested maps when flattening,
+// and otherwise if they have keys that are not options.
+func (o decodeOptions) collect(entries map[string]Entry, prefix string, raw map[string]json.RawMessage) error {
+	options := entryOptions()
+	for k, value := range raw {
+		key := prefix + k
+
+		var nested map[string]json.RawMessage
+		if err := json.Unmarshal(value, &nested); err == nil && nested != nil {
+			if _, isEntry := nested["replace"]; !isEntry {
+				if o.flatten {
+					if err := o.collect(entries, key+".", nested); err != nil {
+						return err
+					}
+					continue
+				}
+
+				for name := range nested {
+					if !options[name] {
+						return &NestedMapError{Key: key}
+					}
+				}
+			}
+		}
+
+		var e Entry
+		if err := json.Unmarshal(value, &e); err != nil {
+			return fmt.Errorf("%q: %v", key, err)
+		}
+
+		if _, exists := entries[key]; exists {
+			return fmt.Errorf("duplicate key %q after flattening", key)
+		}
+		entries[key] = e
+	}
+
+	return nil
+}
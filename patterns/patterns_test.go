@@ -0,0 +1,72 @@
+package patterns_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aswinkarthik/replace-text/patterns"
+	"github.com/aswinkarthik/replace-text/replacer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	t.Run("should decode replacement strings and objects ordered by key", func(t *testing.T) {
+		input := `{
+			"key2": "value2",
+			"id": {"replace": "identifier", "wholeWord": true},
+			"todo": {"replace": "{{.Line}}", "template": true},
+			"version": {"replace": "v2", "maxCount": 1, "nth": 2, "last": true},
+			"key1": "value1"
+		}`
+
+		result, err := patterns.Decode(strings.NewReader(input))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []replacer.Pattern{
+			{Find: "id", Replace: "identifier", WholeWord: true},
+			{Find: "key1", Replace: "value1"},
+			{Find: "key2", Replace: "value2"},
+			{Find: "todo", Replace: "{{.Line}}", Template: true},
+			{Find: "version", Replace: "v2", Limit: replacer.Limit{MaxCount: 1, Nth: 2, Last: true}},
+		}, result)
+	})
+
+	t.Run("should decode regular expression patterns", func(t *testing.T) {
+		input := `{
+			"re:v[0-9]+": "version",
+			"[a-z]+id": {"replace": "identifier", "regexp": true},
+			"key1": "value1"
+		}`
+
+		result, err := patterns.Decode(strings.NewReader(input))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []replacer.Pattern{
+			{Find: "[a-z]+id", Replace: "identifier", Regexp: true},
+			{Find: "key1", Replace: "value1"},
+			{Find: "v[0-9]+", Replace: "version", Regexp: true},
+		}, result)
+	})
+
+	t.Run("should reject unknown options", func(t *testing.T) {
+		input := `{"id": {"replace": "identifier", "wholeWords": true}}`
+
+		_, err := patterns.Decode(strings.NewReader(input))
+
+		assert.EqualError(t, err, `error decoding patterns: "id": json: unknown field "wholeWords"`)
+	})
+
+	t.Run("should reject negative limits", func(t *testing.T) {
+		_, err := patterns.Decode(strings.NewReader(`{"id": {"replace": "identifier", "maxCount": -3}}`))
+		assert.EqualError(t, err, `error decoding patterns: "id": maxCount cannot be negative`)
+
+		_, err = patterns.Decode(strings.NewReader(`[{"find": "id", "replace": "identifier", "nth": -1}]`))
+		assert.EqualError(t, err, "error decoding patterns: pattern 1: nth cannot be negative")
+	})
+
+	t.Run("should reject values that are not strings or objects", func(t *testing.T) {
+		_, err := patterns.Decode(strings.NewReader(`{"id": 1}`))
+
+		assert.Error(t, err)
+	})
+}
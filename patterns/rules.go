@@ -0,0 +1,131 @@
+package patterns
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aswinkarthik/replace-text/replacer"
+)
+
+// ruleOptions are the options of a pattern in an array of patterns
+// besides the options of an Entry
+var ruleOptions = []string{"find", "enabled", "comment"}
+
+// supportedRuleOptions returns the options accepted in an array of patterns
+func supportedRuleOptions() map[string]bool {
+	supported := entryOptions()
+	for _, name := range ruleOptions {
+		supported[name] = true
+	}
+
+	return supported
+}
+
+// entryOptions returns the options of an Entry
+func entryOptions() map[string]bool {
+	options := make(map[string]bool)
+	t := reflect.TypeOf(Entry{})
+	for i := 0; i < t.NumField(); i++ {
+		options[t.Field(i).Tag.Get("json")] = true
+	}
+
+	return options
+}
+
+// decodeRules reads patterns from an array of objects, in the order of the array.
+//
+//	[
+//	  { "find": "id", "replace": "identifier", "wholeWord": true },
+//	  { "find": "ID", "replace": "identifier", "caseInsensitive": true, "comment": "legacy" },
+//	  { "find": "todo", "replace": "done", "enabled": false }
+//	]
+//
+// Patterns with enabled set to false are skipped, and comments are ignored.
+// Patterns are numbered from 1 in errors. The order decides between matches
+// at the same position only with the LeftmostFirst match policy.
+func (o decodeOptions) decodeRules(document json.RawMessage) ([]replacer.Pattern, error) {
+	var rules []map[string]json.RawMessage
+	if err := json.Unmarshal(document, &rules); err != nil {
+		return nil, err
+	}
+
+	supported := supportedRuleOptions()
+	var result []replacer.Pattern
+	seen := map[string]int{}
+	for i, rule := range rules {
+		number := i + 1
+
+		names := make([]string, 0, len(rule))
+		for name := range rule {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if !supported[name] {
+				return nil, fmt.Errorf("pattern %d: option %q is not supported, supported options are %s", number, name, supportedNames(supported))
+			}
+		}
+
+		enabled := true
+		if value, ok := rule["enabled"]; ok {
+			if err := json.Unmarshal(value, &enabled); err != nil {
+				return nil, fmt.Errorf("pattern %d: enabled: %v", number, err)
+			}
+		}
+
+		var find string
+		value, ok := rule["find"]
+		if !ok {
+			return nil, fmt.Errorf("pattern %d: find is missing", number)
+		}
+		if err := json.Unmarshal(value, &find); err != nil {
+			return nil, fmt.Errorf("pattern %d: find: %v", number, err)
+		}
+		if find == "" {
+			return nil, fmt.Errorf("pattern %d: find is empty", number)
+		}
+		if _, ok := rule["replace"]; !ok {
+			return nil, fmt.Errorf("pattern %d: replace is missing", number)
+		}
+
+		for _, name := range ruleOptions {
+			delete(rule, name)
+		}
+		options, err := json.Marshal(rule)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %d: %v", number, err)
+		}
+
+		var e Entry
+		if err := json.Unmarshal(options, &e); err != nil {
+			return nil, fmt.Errorf("pattern %d: %v", number, err)
+		}
+
+		if !enabled {
+			continue
+		}
+		if earlier, exists := seen[find]; exists {
+			return nil, fmt.Errorf("pattern %d: %q duplicates pattern %d", number, find, earlier)
+		}
+		seen[find] = number
+
+		result = append(result, e.Pattern(find))
+	}
+
+	return result, nil
+}
+
+// supportedNames lists the supported options in order
+func supportedNames(supported map[string]bool) string {
+	names := make([]string, 0, len(supported))
+	for name := range supported {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,88 @@
+package patterns_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aswinkarthik/replace-text/patterns"
+	"github.com/aswinkarthik/replace-text/replacer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode_Array(t *testing.T) {
+	t.Run("should decode patterns in the order of the array", func(t *testing.T) {
+		input := `[
+			{ "find": "zeta", "replace": "last" },
+			{ "find": "id", "replace": "identifier", "wholeWord": true, "comment": "ids only" },
+			{ "find": "Key", "replace": "value", "caseInsensitive": true, "maxCount": 2, "enabled": true },
+			{ "find": "re:v[0-9]+", "replace": "version" }
+		]`
+
+		result, err := patterns.Decode(strings.NewReader(input))
+		assert.NoError(t, err)
+		assert.Equal(t, []replacer.Pattern{
+			{Find: "zeta", Replace: "last"},
+			{Find: "id", Replace: "identifier", WholeWord: true},
+			{Find: "Key", Replace: "value", IgnoreCase: true, Limit: replacer.Limit{MaxCount: 2}},
+			{Find: "v[0-9]+", Replace: "version", Regexp: true},
+		}, result)
+	})
+
+	t.Run("should skip disabled patterns", func(t *testing.T) {
+		input := `[
+			{ "find": "a", "replace": "b", "enabled": false },
+			{ "find": "a", "replace": "c" }
+		]`
+
+		result, err := patterns.Decode(strings.NewReader(input))
+		assert.NoError(t, err)
+		assert.Equal(t, []replacer.Pattern{{Find: "a", Replace: "c"}}, result)
+	})
+
+	t.Run("should decode an array in YAML", func(t *testing.T) {
+		input := `
+- find: port
+  replace: 8080
+- find: id
+  replace: identifier
+  wholeWord: true
+`
+		result, err := patterns.Decode(strings.NewReader(input), patterns.WithFormat(patterns.YAML))
+		assert.NoError(t, err)
+		assert.Equal(t, []replacer.Pattern{
+			{Find: "port", Replace: "8080"},
+			{Find: "id", Replace: "identifier", WholeWord: true},
+		}, result)
+	})
+
+	t.Run("should reject unsupported options", func(t *testing.T) {
+		input := `[{ "find": "a", "replace": "b" }, { "find": "a", "replace": "b", "multiline": true }]`
+
+		_, err := patterns.Decode(strings.NewReader(input))
+		assert.EqualError(t, err, `error decoding patterns: pattern 2: option "multiline" is not supported, `+
+			`supported options are caseInsensitive, comment, enabled, find, last, maxCount, nth, regexp, replace, template, wholeWord`)
+	})
+
+	t.Run("should reject patterns without find or replace", func(t *testing.T) {
+		_, err := patterns.Decode(strings.NewReader(`[{ "replace": "b" }]`))
+		assert.EqualError(t, err, "error decoding patterns: pattern 1: find is missing")
+
+		_, err = patterns.Decode(strings.NewReader(`[{ "find": "a" }]`))
+		assert.EqualError(t, err, "error decoding patterns: pattern 1: replace is missing")
+
+		_, err = patterns.Decode(strings.NewReader(`[{ "find": "", "replace": "b" }]`))
+		assert.EqualError(t, err, "error decoding patterns: pattern 1: find is empty")
+	})
+
+	t.Run("should reject options of the wrong type", func(t *testing.T) {
+		_, err := patterns.Decode(strings.NewReader(`[{ "find": "a", "replace": "b", "maxCount": "2" }]`))
+		assert.EqualError(t, err, "error decoding patterns: pattern 1: json: cannot unmarshal string into Go struct field entry.maxCount of type int")
+	})
+
+	t.Run("should reject duplicate patterns", func(t *testing.T) {
+		input := `[{ "find": "a", "replace": "b" }, { "find": "c", "replace": "d" }, { "find": "a", "replace": "e" }]`
+
+		_, err := patterns.Decode(strings.NewReader(input))
+		assert.EqualError(t, err, `error decoding patterns: pattern 3: "a" duplicates pattern 1`)
+	})
+}
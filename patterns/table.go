@@ -0,0 +1,141 @@
+package patterns
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aswinkarthik/replace-text/replacer"
+)
+
+// Default names of the columns of CSV and TSV patterns files.
+// A first row holding them is a header.
+const (
+	DefaultFindColumn    = "find"
+	DefaultReplaceColumn = "replace"
+)
+
+// WithColumns reads the find text and the replacement of CSV and TSV
+// patterns files from the columns with the given names in the header row.
+// Without it, they are the first two columns, and the first row
+// is a header only if it names them DefaultFindColumn and DefaultReplaceColumn.
+func WithColumns(find, replace string) DecodeOption {
+	return func(o *decodeOptions) {
+		o.findColumn = find
+		o.replaceColumn = replace
+	}
+}
+
+// row is a pattern along with the number of the row it is read from
+type row struct {
+	number  int
+	pattern replacer.Pattern
+}
+
+// decodeTable reads patterns from rows of a CSV or TSV patterns file,
+// in the order of the rows. Rows with the same find text are reported
+// along with their row numbers, which start at 1 for the first row.
+func (o decodeOptions) decodeTable(reader io.Reader) ([]replacer.Pattern, error) {
+	r := csv.NewReader(reader)
+	r.FieldsPerRecord = -1
+	if o.format == TSV {
+		r.Comma = '\t'
+		r.LazyQuotes = true
+	}
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	findIndex, replaceIndex, first, err := o.columns(records)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []row
+	var problems []string
+	seen := map[string]row{}
+	for i := first; i < len(records); i++ {
+		number, record := i+1, records[i]
+		if findIndex >= len(record) || replaceIndex >= len(record) {
+			problems = append(problems, fmt.Sprintf("row %d: expected at least %d columns, found %d", number, max(findIndex, replaceIndex)+1, len(record)))
+			continue
+		}
+
+		find := record[findIndex]
+		if find == "" {
+			problems = append(problems, fmt.Sprintf("row %d: find text is empty", number))
+			continue
+		}
+
+		current := row{number: number, pattern: Entry{Replace: record[replaceIndex]}.Pattern(find)}
+		if earlier, exists := seen[find]; exists {
+			if earlier.pattern.Replace == current.pattern.Replace {
+				problems = append(problems, fmt.Sprintf("row %d: %q duplicates row %d", number, find, earlier.number))
+			} else {
+				problems = append(problems, fmt.Sprintf("row %d: %q -> %q conflicts with %q -> %q of row %d",
+					number, find, current.pattern.Replace, find, earlier.pattern.Replace, earlier.number))
+			}
+			continue
+		}
+
+		seen[find] = current
+		rows = append(rows, current)
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid rows:\n%s", strings.Join(problems, "\n"))
+	}
+
+	result := make([]replacer.Pattern, 0, len(rows))
+	for _, r := range rows {
+		result = append(result, r.pattern)
+	}
+
+	return result, nil
+}
+
+// columns returns the indexes of the find and the replace columns,
+// and the index of the first row holding a pattern.
+func (o decodeOptions) columns(records [][]string) (findIndex, replaceIndex, first int, err error) {
+	if o.findColumn == "" && o.replaceColumn == "" {
+		if len(records) > 0 && len(records[0]) >= 2 &&
+			strings.EqualFold(strings.TrimSpace(records[0][0]), DefaultFindColumn) &&
+			strings.EqualFold(strings.TrimSpace(records[0][1]), DefaultReplaceColumn) {
+			return 0, 1, 1, nil
+		}
+		return 0, 1, 0, nil
+	}
+
+	if len(records) == 0 {
+		return 0, 0, 0, fmt.Errorf("header row is missing")
+	}
+
+	findIndex, replaceIndex = -1, -1
+	for i, name := range records[0] {
+		switch strings.TrimSpace(name) {
+		case o.findColumn:
+			findIndex = i
+		case o.replaceColumn:
+			replaceIndex = i
+		}
+	}
+
+	if findIndex < 0 {
+		return 0, 0, 0, fmt.Errorf("column %q not found in header row", o.findColumn)
+	}
+	if replaceIndex < 0 {
+		return 0, 0, 0, fmt.Errorf("column %q not found in header row", o.replaceColumn)
+	}
+
+	return findIndex, replaceIndex, 1, nil
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,71 @@
+package patterns_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/aswinkarthik/replace-text/patterns"
+	"github.com/aswinkarthik/replace-text/replacer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode_Table(t *testing.T) {
+	csvFormat := patterns.WithFormat(patterns.CSV)
+
+	t.Run("should read the first two columns in the order of rows", func(t *testing.T) {
+		input := "oldName,newName\n\"a, b\",\"say \"\"hi\"\"\",ignored\nre:v[0-9]+,version\n"
+
+		result, err := patterns.Decode(strings.NewReader(input), csvFormat)
+		assert.NoError(t, err)
+		assert.Equal(t, []replacer.Pattern{
+			{Find: "oldName", Replace: "newName"},
+			{Find: "a, b", Replace: `say "hi"`},
+			{Find: "v[0-9]+", Replace: "version", Regexp: true},
+		}, result)
+	})
+
+	t.Run("should skip a header naming the default columns", func(t *testing.T) {
+		input := "\xEF\xBB\xBFFind,Replace\nold,new\n"
+
+		result, err := patterns.Decode(strings.NewReader(input), csvFormat)
+		assert.NoError(t, err)
+		assert.Equal(t, []replacer.Pattern{{Find: "old", Replace: "new"}}, result)
+	})
+
+	t.Run("should read named columns", func(t *testing.T) {
+		input := "id\tto\tfrom\n1\tnew\told\n"
+
+		result, err := patterns.Decode(strings.NewReader(input),
+			patterns.WithFormat(patterns.TSV), patterns.WithColumns("from", "to"))
+		assert.NoError(t, err)
+		assert.Equal(t, []replacer.Pattern{{Find: "old", Replace: "new"}}, result)
+
+		_, err = patterns.Decode(strings.NewReader(input),
+			patterns.WithFormat(patterns.TSV), patterns.WithColumns("source", "to"))
+		assert.EqualError(t, err, `error decoding patterns: column "source" not found in header row`)
+	})
+
+	t.Run("should read UTF-16 text", func(t *testing.T) {
+		data := &bytes.Buffer{}
+		for _, unit := range utf16.Encode([]rune("\uFEFFold\tnéw\n")) {
+			assert.NoError(t, binary.Write(data, binary.LittleEndian, unit))
+		}
+
+		result, err := patterns.Decode(data, patterns.WithFormat(patterns.TSV))
+		assert.NoError(t, err)
+		assert.Equal(t, []replacer.Pattern{{Find: "old", Replace: "néw"}}, result)
+	})
+
+	t.Run("should report duplicate and conflicting rows with their numbers", func(t *testing.T) {
+		input := "find,replace\na,1\nb,2\na,1\nb,3\nc\n"
+
+		_, err := patterns.Decode(strings.NewReader(input), csvFormat)
+		assert.EqualError(t, err, "error decoding patterns: invalid rows:\n"+
+			`row 4: "a" duplicates row 2`+"\n"+
+			`row 5: "b" -> "3" conflicts with "b" -> "2" of row 3`+"\n"+
+			`row 6: expected at least 2 columns, found 1`)
+	})
+}
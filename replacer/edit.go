@@ -0,0 +1,69 @@
+package replacer
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Edit is a replacement made in an input
+type Edit struct {
+	// Start is the offset of the first replaced byte in the input
+	Start int64
+	// End is the offset of the byte after the last replaced byte
+	End int64
+	// Replacement is the text written in place of the replaced bytes
+	Replacement string
+}
+
+// Find returns the edits ReplaceFile would make in the input,
+// in the order of their position. Nothing is written.
+// It returns no edits and no error if no matches are found.
+func (r *Replacer) Find(name string, reader io.Reader) ([]Edit, error) {
+	const bufferSize = 8000
+
+	reader, o, err := r.startInput(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newStream(r, name, ioutil.Discard)
+	s.recording = true
+	s.occurrences = o
+	if err := s.run(bufferSize, reader); err != nil && err != ErrNoMatchesFound {
+		return nil, err
+	}
+
+	return s.edits, nil
+}
+
+// Apply copies reader into writer, writing the replacement of every edit
+// in place of the bytes it replaces. Edits must be in the order of their
+// position and must not overlap, like the edits returned by Find.
+func Apply(reader io.Reader, writer io.Writer, edits []Edit) error {
+	var position int64
+	for _, edit := range edits {
+		if edit.Start < position || edit.End < edit.Start {
+			return fmt.Errorf("error applying edit at %d: edits overlap or are out of order", edit.Start)
+		}
+
+		if _, err := io.CopyN(writer, reader, edit.Start-position); err != nil {
+			return fmt.Errorf("error copying data from source to destination: %v", err)
+		}
+
+		if _, err := io.CopyN(ioutil.Discard, reader, edit.End-edit.Start); err != nil {
+			return fmt.Errorf("error skipping replaced data: %v", err)
+		}
+
+		if _, err := io.WriteString(writer, edit.Replacement); err != nil {
+			return fmt.Errorf("error writing replaced strings: %v", err)
+		}
+		position = edit.End
+	}
+
+	if _, err := io.Copy(writer, reader); err != nil {
+		return fmt.Errorf("error copying data from source to destination: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package replacer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplacer_Find(t *testing.T) {
+	t.Run("should return the edits in the order of their position", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{
+			{Find: "key", Replace: "value"},
+			{Find: "{{.Match}}", Replace: "{{.Line}}", Template: true},
+		})
+		assert.NoError(t, err)
+
+		edits, err := r.Find("", strings.NewReader("a key\n{{.Match}} key"))
+		assert.NoError(t, err)
+		assert.Equal(t, []Edit{
+			{Start: 2, End: 5, Replacement: "value"},
+			{Start: 6, End: 16, Replacement: "2"},
+			{Start: 17, End: 20, Replacement: "value"},
+		}, edits)
+	})
+
+	t.Run("should return no edits if there are no matches", func(t *testing.T) {
+		r, err := NewReplacer(map[string]string{"key": "value"})
+		assert.NoError(t, err)
+
+		edits, err := r.Find("", strings.NewReader("nothing"))
+		assert.NoError(t, err)
+		assert.Empty(t, edits)
+	})
+}
+
+func TestApply(t *testing.T) {
+	t.Run("should write replacements of the edits", func(t *testing.T) {
+		writer := &strings.Builder{}
+		err := Apply(strings.NewReader("a key and key"), writer, []Edit{
+			{Start: 2, End: 5, Replacement: "value"},
+			{Start: 10, End: 13, Replacement: ""},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "a value and ", writer.String())
+	})
+
+	t.Run("should return error if edits overlap", func(t *testing.T) {
+		err := Apply(strings.NewReader("a key"), &strings.Builder{}, []Edit{
+			{Start: 2, End: 5, Replacement: "value"},
+			{Start: 3, End: 4, Replacement: "value"},
+		})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,83 @@
+package replacer
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// CaseFolding decides how letter case is compared while matching.
+type CaseFolding int
+
+const (
+	// CaseSensitive compares bytes as they are
+	CaseSensitive CaseFolding = iota
+	// FoldASCII ignores the case of ASCII letters
+	FoldASCII
+	// FoldUnicode ignores the case of letters using Unicode simple case folding.
+	// Input is decoded as UTF-8, invalid bytes are compared as they are.
+	FoldUnicode
+)
+
+// foldKey folds the key the same way input is folded while matching.
+// It also returns the length of the longest input that the folded key can match.
+func foldKey(folding CaseFolding, key string) (string, int) {
+	switch folding {
+	case FoldASCII:
+		folded := []byte(key)
+		for i, b := range folded {
+			folded[i] = foldByte(b)
+		}
+		return string(folded), len(key)
+	case FoldUnicode:
+		folded := make([]byte, 0, len(key))
+		maxLen := 0
+		for i := 0; i < len(key); {
+			r, size := utf8.DecodeRuneInString(key[i:])
+			if r == utf8.RuneError && size == 1 {
+				folded = append(folded, key[i])
+				maxLen++
+			} else {
+				folded = append(folded, string(foldRune(r))...)
+				maxLen += maxRuneLen(r)
+			}
+			i += size
+		}
+		return string(folded), maxLen
+	default:
+		return key, len(key)
+	}
+}
+
+// foldByte maps upper case ASCII letters to lower case
+func foldByte(b byte) byte {
+	if 'A' <= b && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+
+	return b
+}
+
+// foldRune maps every rune of a case folding orbit to the smallest rune in it.
+func foldRune(r rune) rune {
+	folded := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < folded {
+			folded = f
+		}
+	}
+
+	return folded
+}
+
+// maxRuneLen returns the longest UTF-8 encoding among runes
+// of the case folding orbit of r.
+func maxRuneLen(r rune) int {
+	maxLen := utf8.RuneLen(r)
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if l := utf8.RuneLen(f); l > maxLen {
+			maxLen = l
+		}
+	}
+
+	return maxLen
+}
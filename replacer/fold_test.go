@@ -0,0 +1,33 @@
+package replacer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFoldKey(t *testing.T) {
+	t.Run("should not fold keys when case sensitive", func(t *testing.T) {
+		key, maxLen := foldKey(CaseSensitive, "Hello")
+		assert.Equal(t, "Hello", key)
+		assert.Equal(t, 5, maxLen)
+	})
+
+	t.Run("should fold only ASCII letters", func(t *testing.T) {
+		key, maxLen := foldKey(FoldASCII, "HeLLo ÄÖ")
+		assert.Equal(t, "hello ÄÖ", key)
+		assert.Equal(t, 10, maxLen)
+	})
+
+	t.Run("should fold unicode letters to the same key", func(t *testing.T) {
+		upper, _ := foldKey(FoldUnicode, "ΣΑΣ")
+		lower, _ := foldKey(FoldUnicode, "σας")
+		assert.Equal(t, upper, lower)
+	})
+
+	t.Run("should account for longer encodings of folded runes", func(t *testing.T) {
+		// "k" also matches the 3 byte KELVIN SIGN
+		_, maxLen := foldKey(FoldUnicode, "ok")
+		assert.Equal(t, 4, maxLen)
+	})
+}
@@ -1,75 +1,66 @@
 package replacer
 
-// StateMachine represents a single fsm
-// It will hold a cursor in Trie based on what
-// characters have been passed through the fsm.
+// StateMachine represents a single match found by the automaton.
+// StartPosition and EndPosition are the inclusive byte offsets
+// of the matched text in the input.
+// Index is the position of the matched pattern in the replacer's patterns.
+// Groups holds the text of the match followed by its submatches,
+// and is set only for regular expression patterns.
 type StateMachine struct {
 	StartPosition int64
 	EndPosition   int64
 	Terminated    bool
 	ReplaceWith   string
+	Index         int
+	Groups        []string
 	Node          *Node
 }
 
-// StateMachines holds a collection of machines.
-// transitMachines are fsm still in transit nodes.
-// TerminalMachines are fsm that reached the terminal nodes.
+// StateMachines runs the Aho-Corasick automaton built on Root.
+// It holds a single cursor in the Trie based on what
+// characters have been passed through the fsm.
+// TerminalMachines are the matches found so far.
 type StateMachines struct {
-	transitMachines  []*StateMachine
+	current          *Node
 	TerminalMachines []*StateMachine
 	Root             *Node
 }
 
-// NewStateMachines is a constructor for creating StateMachines
+// NewStateMachines is a constructor for creating StateMachines.
+// Links of the trie are built if they are not built yet.
 func NewStateMachines(root *Node) *StateMachines {
+	if !root.linked {
+		root.BuildLinks()
+	}
+
 	return &StateMachines{
-		transitMachines:  make([]*StateMachine, 0),
+		current:          root,
 		TerminalMachines: make([]*StateMachine, 0),
 		Root:             root,
 	}
 }
 
 // Accept will take in a single character and its position.
-// It will pass through all the transit machines and transition it to
-// the next state. If there is no state, the transit machine is discarded
-// TerminalMachines hold all fsm that reached the terminal nodes.
+// It advances the cursor to the next state, following failure links
+// when there is no direct transition.
+// Every terminal node reachable from the new state through output links
+// is recorded in TerminalMachines, longest match first.
 func (s *StateMachines) Accept(ch byte, pos int64) {
-	resultMachines := make([]*StateMachine, 0, len(s.transitMachines))
+	s.current = s.current.step(ch, s.Root)
 
-	for _, m := range s.transitMachines {
-		nextNode, err := m.Node.Next(ch)
-		if err != nil {
-			continue
-		}
-
-		m.Node = nextNode
-		if nextNode.Terminates() {
-			m.EndPosition = pos
-			m.Terminated = true
-			m.ReplaceWith = nextNode.value
-
-			s.TerminalMachines = append(s.TerminalMachines, m)
-		} else {
-			resultMachines = append(resultMachines, m)
-		}
+	terminal := s.current
+	if !terminal.terminal {
+		terminal = terminal.output
 	}
 
-	nextNode, err := s.Root.Next(ch)
-	if err == nil {
-		m := &StateMachine{
-			StartPosition: pos,
-			Node:          nextNode,
-		}
-		if nextNode.Terminates() {
-			m.Terminated = true
-			m.EndPosition = pos
-			m.ReplaceWith = nextNode.value
-
-			s.TerminalMachines = append(s.TerminalMachines, m)
-		} else {
-			resultMachines = append(resultMachines, m)
-		}
+	for ; terminal != nil; terminal = terminal.output {
+		s.TerminalMachines = append(s.TerminalMachines, &StateMachine{
+			StartPosition: pos - int64(terminal.depth) + 1,
+			EndPosition:   pos,
+			Terminated:    true,
+			ReplaceWith:   terminal.value,
+			Index:         terminal.index,
+			Node:          terminal,
+		})
 	}
-
-	s.transitMachines = resultMachines
 }
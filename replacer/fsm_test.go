@@ -43,3 +43,29 @@ func TestStateMachines_Accept(t *testing.T) {
 		assert.Equal(t, expectedEndPositions, actualEndPositions)
 	})
 }
+
+func TestStateMachines_AcceptOverlapping(t *testing.T) {
+	t.Run("should report matches that end inside another match", func(t *testing.T) {
+		node := replacer.NewNode()
+		{
+			assert.NoError(t, node.AddString("she"))
+			assert.NoError(t, node.AddString("he"))
+			assert.NoError(t, node.AddString("his"))
+		}
+
+		fsm := replacer.NewStateMachines(node)
+		text := "ushers and this"
+		{
+			for i := 0; i < len(text); i++ {
+				fsm.Accept(text[i], int64(i))
+			}
+		}
+
+		actualResults := make([]string, 0)
+		for _, m := range fsm.TerminalMachines {
+			actualResults = append(actualResults, text[m.StartPosition:m.EndPosition+1])
+		}
+
+		assert.Equal(t, []string{"she", "he", "his"}, actualResults)
+	})
+}
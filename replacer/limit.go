@@ -0,0 +1,164 @@
+package replacer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Limit restricts which occurrences of a pattern are replaced.
+// Occurrences are counted after overlapping matches are resolved,
+// and the zero value replaces every occurrence.
+type Limit struct {
+	// MaxCount replaces only the first MaxCount occurrences, if it is not zero
+	MaxCount int
+	// Nth replaces only the Nth occurrence, if it is not zero
+	Nth int
+	// Last replaces only the last occurrence
+	Last bool
+}
+
+// allows returns true if the occurrence out of total occurrences must be replaced
+func (l Limit) allows(occurrence, total int) bool {
+	if l.MaxCount > 0 && occurrence > l.MaxCount {
+		return false
+	}
+
+	if l.Nth > 0 && occurrence != l.Nth {
+		return false
+	}
+
+	if l.Last && occurrence != total {
+		return false
+	}
+
+	return true
+}
+
+// LimitScope decides where occurrences are counted for limits.
+type LimitScope int
+
+const (
+	// PerInput counts occurrences separately for every input replaced
+	PerInput LimitScope = iota
+	// PerRun counts occurrences across every input replaced by the replacer
+	PerRun
+)
+
+// WithLimit restricts the occurrences replaced across all the patterns.
+// Occurrences of every pattern are counted together for this limit,
+// while limits of a pattern count only its own occurrences.
+func WithLimit(limit Limit) Option {
+	return func(r *Replacer) {
+		r.limits.global = limit
+	}
+}
+
+// WithLimitScope sets where occurrences are counted for limits.
+// Defaults to PerInput. A replacer counting occurrences PerRun
+// is not safe for concurrent use.
+func WithLimitScope(scope LimitScope) Option {
+	return func(r *Replacer) {
+		r.limits.scope = scope
+	}
+}
+
+// limits holds the limits of a replacer. Occurrences are counted in the PerInput
+// scope by every stream, while the PerRun scope counts them on the replacer,
+// which is why a replacer with that scope is not safe for concurrent use.
+type limits struct {
+	global Limit
+	scope  LimitScope
+	// limited is set if any limit is set, otherwise occurrences are not counted
+	limited bool
+	// needsTotals is set if any limit replaces the last occurrence
+	needsTotals bool
+	// run counts occurrences across inputs in the PerRun scope
+	run *occurrences
+}
+
+// occurrences counts the occurrences of every pattern and of all of them together.
+// Totals are known only after counting the matches in a separate pass,
+// which is needed only if a limit replaces the last occurrence.
+type occurrences struct {
+	seen      []int
+	seenAll   int
+	totals    []int
+	totalsAll int
+}
+
+func newOccurrences(patterns int) *occurrences {
+	return &occurrences{seen: make([]int, patterns), totals: make([]int, patterns)}
+}
+
+// allow counts an occurrence of the pattern and returns true
+// if the occurrence must be replaced.
+func (o *occurrences) allow(global, patternLimit Limit, index int) bool {
+	o.seen[index]++
+	o.seenAll++
+
+	return patternLimit.allows(o.seen[index], o.totals[index]) &&
+		global.allows(o.seenAll, o.totalsAll)
+}
+
+// total counts an occurrence of the pattern while counting matches
+func (o *occurrences) total(index int) {
+	o.totals[index]++
+	o.totalsAll++
+}
+
+// Count finds the occurrences of patterns in reader, without replacing them,
+// and adds them to the totals needed by limits replacing the last occurrence.
+//
+// With the PerRun scope every input must be counted before any is replaced.
+// With the PerInput scope this is done by the replacer itself.
+func (r *Replacer) Count(reader io.Reader) error {
+	return r.count(r.limits.run, reader)
+}
+
+// count adds the occurrences of patterns in reader to the totals of o
+func (r *Replacer) count(o *occurrences, reader io.Reader) error {
+	const bufferSize = 8000
+
+	s := newStream(r, "", ioutil.Discard)
+	s.counting = true
+	s.occurrences = o
+	if err := s.run(bufferSize, reader); err != nil && err != ErrNoMatchesFound {
+		return err
+	}
+
+	return nil
+}
+
+// countInput counts the occurrences in the input before it is replaced.
+// The input is read twice, so a reader that cannot seek is read into memory.
+func (r *Replacer) countInput(o *occurrences, reader io.Reader) (io.Reader, error) {
+	var start int64
+	seeker, ok := reader.(io.ReadSeeker)
+	if ok {
+		// Files like pipes implement io.Seeker but fail to seek
+		var err error
+		start, err = seeker.Seek(0, io.SeekCurrent)
+		ok = err == nil
+	}
+
+	if !ok {
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error reading input: %v", err)
+		}
+		seeker = bytes.NewReader(data)
+		start = 0
+	}
+
+	if err := r.count(o, seeker); err != nil {
+		return nil, err
+	}
+
+	if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("error seeking input: %v", err)
+	}
+
+	return seeker, nil
+}
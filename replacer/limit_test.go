@@ -0,0 +1,123 @@
+package replacer
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplacer_Limits(t *testing.T) {
+	input := "a b a b a b"
+
+	t.Run("should replace only limited occurrences of a pattern", func(t *testing.T) {
+		tests := []struct {
+			limit    Limit
+			expected string
+		}{
+			{Limit{}, "X b X b X b"},
+			{Limit{MaxCount: 2}, "X b X b a b"},
+			{Limit{Nth: 2}, "a b X b a b"},
+			{Limit{Last: true}, "a b a b X b"},
+			{Limit{MaxCount: 1, Nth: 2}, "a b a b a b"},
+		}
+
+		for _, test := range tests {
+			r, err := NewPatternReplacer([]Pattern{
+				{Find: "a", Replace: "X", Limit: test.limit},
+				{Find: "b", Replace: "b"},
+			})
+			assert.NoError(t, err)
+
+			output, _ := r.ReplaceString(input)
+			assert.Equal(t, test.expected, output, "%+v", test.limit)
+		}
+	})
+
+	t.Run("should count occurrences of all patterns together for global limits", func(t *testing.T) {
+		r, err := NewReplacer(map[string]string{"a": "X", "b": "Y"}, WithLimit(Limit{Nth: 4}))
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString(input)
+		assert.NoError(t, err)
+		assert.Equal(t, "a b a Y a b", output)
+	})
+
+	t.Run("should replace last occurrence of readers that cannot seek", func(t *testing.T) {
+		r, err := NewReplacer(map[string]string{"a": "X"}, WithLimit(Limit{Last: true}))
+		assert.NoError(t, err)
+
+		writer := &bytes.Buffer{}
+		assert.NoError(t, r.Replace(iotest.OneByteReader(strings.NewReader(input)), writer))
+		assert.Equal(t, "a b a b X b", writer.String())
+	})
+
+	t.Run("should count occurrences per input by default", func(t *testing.T) {
+		r, err := NewReplacer(map[string]string{"a": "X"}, WithLimit(Limit{MaxCount: 1}))
+		assert.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			output, err := r.ReplaceString("a a")
+			assert.NoError(t, err)
+			assert.Equal(t, "X a", output)
+		}
+	})
+
+	t.Run("should count occurrences across inputs for run scope", func(t *testing.T) {
+		r, err := NewReplacer(map[string]string{"a": "X"}, WithLimit(Limit{Nth: 3}), WithLimitScope(PerRun))
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString("a a")
+		assert.Equal(t, ErrNoMatchesFound, err)
+		assert.Equal(t, "a a", output)
+
+		output, err = r.ReplaceString("a a")
+		assert.NoError(t, err)
+		assert.Equal(t, "X a", output)
+	})
+
+	t.Run("should replace last occurrence across inputs counted for run scope", func(t *testing.T) {
+		r, err := NewReplacer(map[string]string{"a": "X"}, WithLimit(Limit{Last: true}), WithLimitScope(PerRun))
+		assert.NoError(t, err)
+
+		inputs := []string{"a a", "a a", "b"}
+		for _, in := range inputs {
+			assert.NoError(t, r.Count(strings.NewReader(in)))
+		}
+
+		outputs := make([]string, 0, len(inputs))
+		for _, in := range inputs {
+			output, _ := r.ReplaceString(in)
+			outputs = append(outputs, output)
+		}
+		assert.Equal(t, []string{"a a", "a X", "b"}, outputs)
+	})
+}
+
+func TestReplacer_Concurrent(t *testing.T) {
+	t.Run("should replace inputs concurrently with limits counted per input", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{
+			{Find: "a", Replace: "X", Limit: Limit{Last: true}},
+			{Find: "b", Replace: "Y", Limit: Limit{Nth: 1}},
+		})
+		assert.NoError(t, err)
+
+		var wg sync.WaitGroup
+		outputs := make([]string, 8)
+		for i := range outputs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				outputs[i], _ = r.ReplaceString("a b a b a")
+			}(i)
+		}
+		wg.Wait()
+
+		for _, output := range outputs {
+			assert.Equal(t, "a Y a b X", output)
+		}
+	})
+}
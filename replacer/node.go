@@ -9,23 +9,44 @@ import (
 // It can be either terminal or intermediate
 // All character bytes are stored on edges
 // Each key of map next is an edge leading to the next node
+//
+// Once BuildLinks is called, every node also carries the
+// Aho-Corasick failure and output links.
 type Node struct {
 	terminal bool
 	next     map[byte]*Node
 	value    string
+	// index is the position of the pattern terminating here,
+	// used as its priority when resolving overlapping matches
+	index int
+
+	// depth is the length of the path from the root to this node
+	depth int
+	// fail points to the node representing the longest proper suffix
+	// of this node's path that is also present in the trie
+	fail *Node
+	// output points to the nearest terminal node reachable through fail links
+	output *Node
+	// linked is set on the root once BuildLinks has run
+	linked bool
 }
 
-// ErrPrefixConflict represents an error where the prefix of the
-// added string is already present in the trie.
-// E.g if "hell" is already added, adding "hello" has a common prefix "hell"
-// This causes ambiguity when finding for words on when to quit.
-// Hence, this error is returned on AddString
+// ErrDuplicateKey is returned on AddString or Put
+// if the exact same string is already present in the trie.
+// A string can still be a prefix of another string in the trie,
+// in which case both the nodes are terminal.
+var ErrDuplicateKey = fmt.Errorf("conflict: the string already exists")
+
+// ErrPrefixConflict was returned on AddString when a prefix of the
+// added string was already present in the trie.
+//
+// Deprecated: keys can be prefixes of other keys, and it is no longer returned.
 var ErrPrefixConflict = fmt.Errorf("conflict: a prefix of the world already exists")
 
-// ErrContainsConflict represents an error where the given added string
-// is contained in a string in the trie.
-// E.g if "hello" is already added, then adding "hell" becomes ambigous
-// as it is contained inside the existing trie.
+// ErrContainsConflict was returned on AddString when the added string
+// was contained in a string in the trie.
+//
+// Deprecated: keys can be prefixes of other keys, and it is no longer returned.
 var ErrContainsConflict = fmt.Errorf("conflict: a prefix of the world already exists")
 
 // ErrNodeNotFound is returned if there are no matching next nodes
@@ -43,7 +64,8 @@ var ErrKeyNotSupported = fmt.Errorf("empty key not supported")
 // AddString will add the given string into the Trie structure
 // It marks the node of the last edge as terminal
 func (n *Node) AddString(s string) error {
-	return n.put(s, "")
+	n.linked = false
+	return n.put(s, "", 0)
 }
 
 // Terminates returns true if the node is a terminal node
@@ -79,7 +101,7 @@ func (n *Node) MarshalJSON() ([]byte, error) {
 	return json.Marshal(readableMap)
 }
 
-func (n *Node) put(path string, leafValue string) error {
+func (n *Node) put(path string, leafValue string, index int) error {
 	if len(path) == 0 {
 		return fmt.Errorf("empty string not accepted")
 	}
@@ -89,30 +111,25 @@ func (n *Node) put(path string, leafValue string) error {
 	nextNode, nextNodeExists := n.next[ch]
 	if !nextNodeExists {
 		nextNode = NewNode()
+		nextNode.depth = n.depth + 1
 	}
 	n.next[ch] = nextNode
 
-	lastCharacter := len(path) == 1
-
-	// Last character but is not a new node to be created
-	if lastCharacter && nextNodeExists {
-		return ErrContainsConflict
-	}
-
-	// Not the last character, but found a terminal node already
-	if !lastCharacter && nextNode.Terminates() {
-		return ErrPrefixConflict
+	// Recurse the rest of the string if this is not the last character
+	if len(path) > 1 {
+		return nextNode.put(restOfString, leafValue, index)
 	}
 
-	// Last character and a new node
-	if lastCharacter {
-		nextNode.terminal = true
-		nextNode.value = leafValue
-		return nil
+	// Last character, the node can be an inner node of a longer string
+	// but it must not be terminal already
+	if nextNode.Terminates() {
+		return ErrDuplicateKey
 	}
 
-	// Recurse the rest of the string otherwise
-	return nextNode.put(restOfString, leafValue)
+	nextNode.terminal = true
+	nextNode.value = leafValue
+	nextNode.index = index
+	return nil
 }
 
 // Next accepts a character and returns the next node continuing the chain
@@ -124,6 +141,51 @@ func (n *Node) Next(ch byte) (*Node, error) {
 	return nil, ErrNodeNotFound
 }
 
+// BuildLinks computes the failure and output links of every node
+// in the trie rooted at n, turning it into an Aho-Corasick automaton.
+// It must be called again whenever strings are added to the trie.
+func (n *Node) BuildLinks() {
+	n.fail = nil
+	n.output = nil
+
+	queue := make([]*Node, 0, len(n.next))
+	for _, child := range n.next {
+		child.fail = n
+		child.output = nil
+		queue = append(queue, child)
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for ch, child := range current.next {
+			child.fail = current.fail.step(ch, n)
+			if child.fail.terminal {
+				child.output = child.fail
+			} else {
+				child.output = child.fail.output
+			}
+
+			queue = append(queue, child)
+		}
+	}
+
+	n.linked = true
+}
+
+// step returns the node reached by following ch from n,
+// falling back through failure links till root.
+func (n *Node) step(ch byte, root *Node) *Node {
+	for current := n; current != nil; current = current.fail {
+		if nextNode, exists := current.next[ch]; exists {
+			return nextNode
+		}
+	}
+
+	return root
+}
+
 // AddReplacement provides a way to define what string
 // needs to be found and what to be replaced with.
 // The string to be found is the path.
@@ -135,7 +197,8 @@ func (n *Node) AddReplacement(old, new string) error {
 // Put can be used to insert to Key-Value pair into the node.
 // This allows PUT implementation for the node so that it can be used as a Map.
 func (n *Node) Put(key, value string) error {
-	return n.put(key, value)
+	n.linked = false
+	return n.put(key, value, 0)
 }
 
 // Get can be used to query a Key and retrieve the value from the node.
@@ -152,10 +215,14 @@ func (n *Node) Get(key string) (string, error) {
 		return "", ErrKeyNotFound
 	}
 
+	if len(restOfString) > 0 {
+		return nextNode.Get(restOfString)
+	}
+
 	if nextNode.Terminates() {
 		return nextNode.value, nil
 	}
-	return nextNode.Get(restOfString)
+	return "", ErrKeyNotFound
 }
 
 // Contains tests if the string k is present inside the
@@ -173,8 +240,9 @@ func (n *Node) Contains(k string) bool {
 		return false
 	}
 
-	if nextNode.Terminates() {
-		return true
+	if len(restOfString) > 0 {
+		return nextNode.Contains(restOfString)
 	}
-	return nextNode.Contains(restOfString)
+
+	return nextNode.Terminates()
 }
@@ -41,18 +41,29 @@ func TestNode_AddString(t *testing.T) {
 		}
 	})
 
-	t.Run("should throw error if a string that is a prefix of the given string is already present in trie", func(t *testing.T) {
+	t.Run("should add a string when a prefix of it is already present in trie", func(t *testing.T) {
 		node := replacer.NewNode()
 
 		assert.NoError(node.AddString("hell"))
-		assert.EqualError(node.AddString("hello"), replacer.ErrPrefixConflict.Error())
+		assert.NoError(node.AddString("hello"))
+		assert.True(node.Contains("hell"))
+		assert.True(node.Contains("hello"))
 	})
 
-	t.Run("should throw error if the given string is a prefix of an existing string in trie", func(t *testing.T) {
+	t.Run("should add a string that is a prefix of an existing string in trie", func(t *testing.T) {
 		node := replacer.NewNode()
 
 		assert.NoError(node.AddString("hello"))
-		assert.EqualError(node.AddString("hell"), replacer.ErrContainsConflict.Error())
+		assert.NoError(node.AddString("hell"))
+		assert.True(node.Contains("hell"))
+		assert.True(node.Contains("hello"))
+	})
+
+	t.Run("should throw error if the same string is already present in trie", func(t *testing.T) {
+		node := replacer.NewNode()
+
+		assert.NoError(node.AddString("hello"))
+		assert.EqualError(node.AddString("hello"), replacer.ErrDuplicateKey.Error())
 	})
 }
 
@@ -119,6 +130,30 @@ func TestNode_Get(t *testing.T) {
 		assert.NoError(err)
 		assert.Equal("random-value", val)
 	})
+
+	t.Run("should return values for keys that are prefixes of each other", func(t *testing.T) {
+		node := replacer.NewNode()
+		assert.NoError(node.Put("https", "secure"))
+		assert.NoError(node.Put("http", "plain"))
+
+		{
+			val, err := node.Get("http")
+			assert.NoError(err)
+			assert.Equal("plain", val)
+		}
+
+		{
+			val, err := node.Get("https")
+			assert.NoError(err)
+			assert.Equal("secure", val)
+		}
+
+		{
+			val, err := node.Get("htt")
+			assert.Equal(replacer.ErrKeyNotFound, err)
+			assert.Equal("", val)
+		}
+	})
 }
 
 func TestNode_Next(t *testing.T) {
@@ -0,0 +1,233 @@
+package replacer
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// regexpPattern is a compiled regular expression pattern
+// along with its position in the replacer's patterns.
+type regexpPattern struct {
+	index    int
+	re       *regexp.Regexp
+	template []byte
+	// raw is set for template patterns, whose replacement is rendered
+	// with the submatches instead of being expanded,
+	// and for literal patterns whose replacement is used as is
+	raw bool
+	// keys maps the second submatch of delimited patterns to the index
+	// of the pattern matched, whose replacement is used
+	keys map[string]int
+}
+
+// groupReference matches references to submatches in a replacement.
+// $$ is matched as well so that escaped dollars are skipped.
+var groupReference = regexp.MustCompile(`\$(\$|[a-zA-Z0-9_]+|\{[^}]*\})`)
+
+// newRegexpPattern compiles the pattern and validates that every submatch
+// referred in the replacement exists in the expression.
+// Replacements of template patterns are not expanded.
+func newRegexpPattern(index int, folding CaseFolding, p Pattern) (regexpPattern, error) {
+	if p.IgnoreCase {
+		folding = FoldUnicode
+	}
+
+	re, err := compileRegexp(folding, p.Find)
+	if err != nil {
+		return regexpPattern{}, err
+	}
+
+	if p.Template {
+		return regexpPattern{index: index, re: re, template: []byte(p.Replace), raw: true}, nil
+	}
+
+	names := make(map[string]bool)
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			names[name] = true
+		}
+	}
+
+	for _, ref := range groupReference.FindAllStringSubmatch(p.Replace, -1) {
+		name := ref[1]
+		if name == "$" {
+			continue
+		}
+		if name[0] == '{' {
+			name = name[1 : len(name)-1]
+		}
+
+		if n, err := strconv.Atoi(name); err == nil {
+			if n > re.NumSubexp() {
+				return regexpPattern{}, fmt.Errorf("replacement refers to group %d but expression has %d groups", n, re.NumSubexp())
+			}
+		} else if !names[name] {
+			return regexpPattern{}, fmt.Errorf("replacement refers to unknown group %q", name)
+		}
+	}
+
+	return regexpPattern{index: index, re: re, template: []byte(p.Replace)}, nil
+}
+
+// newLiteralRegexpPattern matches the literal text of the pattern regardless of case.
+// The text cannot span lines, as regular expressions are matched within a line.
+func newLiteralRegexpPattern(index int, p Pattern) (regexpPattern, error) {
+	if strings.Contains(p.Find, "\n") {
+		return regexpPattern{}, fmt.Errorf("patterns ignoring case cannot contain a newline unless Unicode case folding is used for all patterns")
+	}
+
+	re, err := compileRegexp(FoldUnicode, regexp.QuoteMeta(p.Find))
+	if err != nil {
+		return regexpPattern{}, err
+	}
+
+	return regexpPattern{index: index, re: re, template: []byte(p.Replace), raw: true}, nil
+}
+
+// newDelimitedPattern matches keys between the prefix and the suffix
+// with spaces or tabs inside the delimiters, to be looked up in keys.
+// Longer keys are preferred, as only one alternative matches at a position.
+func newDelimitedPattern(folding CaseFolding, prefix, suffix string, keys map[string]int) (regexpPattern, error) {
+	alternatives := make([]string, 0, len(keys))
+	for key := range keys {
+		alternatives = append(alternatives, regexp.QuoteMeta(key))
+	}
+	sort.Slice(alternatives, func(i, j int) bool {
+		if len(alternatives[i]) != len(alternatives[j]) {
+			return len(alternatives[i]) > len(alternatives[j])
+		}
+		return alternatives[i] < alternatives[j]
+	})
+
+	before, after := "()", "()"
+	if prefix != "" {
+		before = `([ \t]*)`
+	}
+	if suffix != "" {
+		after = `([ \t]*)`
+	}
+
+	expr := regexp.QuoteMeta(prefix) + before + "(" + strings.Join(alternatives, "|") + ")" + after + regexp.QuoteMeta(suffix)
+	re, err := compileRegexp(folding, expr)
+	if err != nil {
+		return regexpPattern{}, err
+	}
+
+	return regexpPattern{re: re, keys: keys}, nil
+}
+
+// expand returns the replacement for the match at loc in src.
+// $1, ${1} or ${name} in the replacement are expanded to the text of
+// the submatch, and $$ is expanded to a literal $.
+func (p regexpPattern) expand(src []byte, loc []int) string {
+	if p.raw {
+		return string(p.template)
+	}
+
+	return string(p.re.Expand(nil, p.template, src, loc))
+}
+
+// compileRegexp compiles the pattern as an RE2 expression.
+// Case folding of the replacer makes the expression case insensitive.
+func compileRegexp(folding CaseFolding, expr string) (*regexp.Regexp, error) {
+	if folding != CaseSensitive {
+		expr = "(?i)" + expr
+	}
+
+	return regexp.Compile(expr)
+}
+
+// matchLines runs the regular expression patterns on every line completed
+// by the bytes passed through the state machines since the last call.
+// At the end of input, the last line is complete as well.
+// Regular expressions never match across lines, and empty matches are ignored.
+func (s *stream) matchLines(eof bool) {
+	for s.scanned < s.position {
+		unscanned := s.pending[s.scanned-s.pendingStart : s.position-s.pendingStart]
+		i := bytes.IndexByte(unscanned, '\n')
+		if i < 0 {
+			s.scanned = s.position
+			break
+		}
+
+		newline := s.scanned + int64(i)
+		s.matchLine(s.lineStart, newline)
+		s.lineStart = newline + 1
+		s.scanned = newline + 1
+	}
+
+	if eof && s.lineStart < s.position {
+		s.matchLine(s.lineStart, s.position)
+		s.lineStart = s.position
+	}
+}
+
+// matchLine adds the matches of regular expression patterns
+// in the input between start and end as candidates.
+// Replacements of the candidates are expanded with their submatches.
+func (s *stream) matchLine(start, end int64) {
+	line := s.pending[start-s.pendingStart : end-s.pendingStart]
+	for _, p := range s.replacer.regexps {
+		if p.keys != nil {
+			s.matchDelimited(p, start, line)
+			continue
+		}
+
+		for _, loc := range p.re.FindAllSubmatchIndex(line, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+
+			groups := make([]string, len(loc)/2)
+			for i := range groups {
+				if loc[2*i] >= 0 {
+					groups[i] = string(line[loc[2*i]:loc[2*i+1]])
+				}
+			}
+
+			s.candidates = append(s.candidates, &StateMachine{
+				StartPosition: start + int64(loc[0]),
+				EndPosition:   start + int64(loc[1]) - 1,
+				Terminated:    true,
+				ReplaceWith:   p.expand(line, loc),
+				Index:         p.index,
+				Groups:        groups,
+			})
+		}
+	}
+}
+
+// matchDelimited adds the matches of a delimited pattern as candidates of the
+// patterns of their keys. Matches without spaces are left to the trie.
+// Keys the expression folds differently than the trie are not found,
+// in which case the text is searched again for keys starting inside it.
+func (s *stream) matchDelimited(p regexpPattern, start int64, line []byte) {
+	for offset := 0; offset < len(line); {
+		loc := p.re.FindSubmatchIndex(line[offset:])
+		if loc == nil {
+			return
+		}
+
+		key, _ := foldKey(s.replacer.folding, string(line[offset+loc[4]:offset+loc[5]]))
+		index, ok := p.keys[key]
+		if !ok {
+			offset += loc[0] + 1
+			continue
+		}
+
+		if loc[2] != loc[3] || loc[6] != loc[7] {
+			s.candidates = append(s.candidates, &StateMachine{
+				StartPosition: start + int64(offset+loc[0]),
+				EndPosition:   start + int64(offset+loc[1]) - 1,
+				Terminated:    true,
+				ReplaceWith:   s.replacer.patterns[index].Replace,
+				Index:         index,
+			})
+		}
+		offset += loc[1]
+	}
+}
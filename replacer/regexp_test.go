@@ -0,0 +1,87 @@
+package replacer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplacer_Regexp(t *testing.T) {
+	t.Run("should replace regular expression and literal patterns together", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{
+			{Find: `v[0-9]+\.[0-9]+`, Replace: "VERSION", Regexp: true},
+			{Find: "key1", Replace: "value1"},
+		})
+		assert.NoError(t, err)
+
+		input := "key1 is at v1.2\nand key1 at v10.20"
+		expected := "value1 is at VERSION\nand value1 at VERSION"
+		for _, bufferSize := range []int{1, 4, 100} {
+			writer := &bytes.Buffer{}
+			assert.NoError(t, r.run(bufferSize, "", strings.NewReader(input), writer))
+			assert.Equal(t, expected, writer.String())
+		}
+	})
+
+	t.Run("should resolve overlaps between regular expression and literal matches", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{
+			{Find: "ab", Replace: "1"},
+			{Find: "b+c", Replace: "2", Regexp: true},
+		})
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString("abbbc bbc")
+		assert.NoError(t, err)
+		assert.Equal(t, "1bbc 2", output)
+	})
+
+	t.Run("should not match across lines or match empty strings", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{
+			{Find: `a\s*b|x*`, Replace: "-", Regexp: true},
+		})
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString("a\nb a b")
+		assert.NoError(t, err)
+		assert.Equal(t, "a\nb -", output)
+	})
+
+	t.Run("should apply case folding and whole words to regular expressions", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{
+			{Find: `i[a-z]`, Replace: "X", Regexp: true, WholeWord: true},
+		}, WithCaseFolding(FoldASCII))
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString("ID valid Is")
+		assert.NoError(t, err)
+		assert.Equal(t, "X valid X", output)
+	})
+
+	t.Run("should expand submatches in replacements", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{
+			{Find: `v(\d+)\.(\d+)`, Replace: "v$2.${1}0", Regexp: true},
+			{Find: `(?P<name>[a-z]+)_id`, Replace: "${name}_new costs $$1", Regexp: true},
+			{Find: "plain", Replace: "$1"},
+		})
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString("v1.2 user_id plain")
+		assert.NoError(t, err)
+		assert.Equal(t, "v2.10 user_new costs $1 $1", output)
+	})
+
+	t.Run("should return error for replacements referring to unknown groups", func(t *testing.T) {
+		_, err := NewPatternReplacer([]Pattern{{Find: `v(\d+)`, Replace: "$2", Regexp: true}})
+		assert.EqualError(t, err, `error creating replacer for "v(\\d+)": replacement refers to group 2 but expression has 1 groups`)
+
+		_, err = NewPatternReplacer([]Pattern{{Find: `(?P<major>\d+)`, Replace: "${minor}", Regexp: true}})
+		assert.EqualError(t, err, `error creating replacer for "(?P<major>\\d+)": replacement refers to unknown group "minor"`)
+	})
+
+	t.Run("should return error for invalid regular expressions", func(t *testing.T) {
+		_, err := NewPatternReplacer([]Pattern{{Find: "a(", Regexp: true}})
+		assert.EqualError(t, err, "error creating replacer for \"a(\": error parsing regexp: missing closing ): `a(`")
+	})
+}
@@ -3,44 +3,300 @@ package replacer
 import (
 	"fmt"
 	"io"
+	"sort"
 	"strings"
+	"text/template"
 )
 
 // Replacer is the struct responsible for doing IO operations
 type Replacer struct {
-	root *Node
+	root     *Node
+	regexps  []regexpPattern
+	patterns []Pattern
+	policy   MatchPolicy
+	folding  CaseFolding
+
+	// templates holds parsed templates of templated patterns by their index
+	templates     []*template.Template
+	templatesOnly bool
+	env           map[string]string
+
+	// delimiterSpaces makes literal patterns between prefix and suffix
+	// match spaces and tabs inside the delimiters as well
+	delimiterSpaces bool
+	prefix, suffix  string
+
+	limits limits
+
+	wordChars     WordChars
+	wholeWords    bool
+	hasWholeWords bool
+	// maxLen is the length of the longest input a pattern can match
+	maxLen int
+	// maxDepth is the length of the longest key in the trie
+	maxDepth int
+}
+
+// Pattern is a single text to find along with its replacement.
+// WholeWord patterns are replaced only when they are not part of a larger word.
+// Regexp patterns treat Find as an RE2 regular expression matched within a line.
+// Their Replace can refer to submatches as $1, ${1} or ${name}, and $$ is a literal $.
+// Replace of literal patterns is always used as is.
+// Template patterns treat Replace as a text/template rendered with a MatchContext.
+// IgnoreCase patterns match regardless of letter case, using Unicode case folding,
+// even if the replacer is case sensitive. Unless the replacer uses Unicode case folding,
+// literal IgnoreCase patterns are matched like regular expressions, within a line.
+// Limit restricts which occurrences of the pattern are replaced.
+type Pattern struct {
+	Find       string
+	Replace    string
+	WholeWord  bool
+	Regexp     bool
+	Template   bool
+	IgnoreCase bool
+	Limit
+}
+
+// Option configures optional behavior of a Replacer
+type Option func(r *Replacer)
+
+// WithMatchPolicy sets the policy used to pick between overlapping matches.
+// Defaults to LeftmostLongest.
+func WithMatchPolicy(policy MatchPolicy) Option {
+	return func(r *Replacer) {
+		r.policy = policy
+	}
+}
+
+// WithCaseFolding makes the replacer ignore letter case while matching.
+// Keys are folded while building the trie and input is folded while matching.
+// Defaults to CaseSensitive.
+func WithCaseFolding(folding CaseFolding) Option {
+	return func(r *Replacer) {
+		r.folding = folding
+	}
+}
+
+// WithWholeWords makes every pattern match only whole words,
+// the same as setting WholeWord on each of them.
+func WithWholeWords() Option {
+	return func(r *Replacer) {
+		r.wholeWords = true
+	}
+}
+
+// WithWordChars sets the characters that form words for whole word patterns.
+// Defaults to DefaultWordChars.
+func WithWordChars(chars WordChars) Option {
+	return func(r *Replacer) {
+		r.wordChars = chars
+	}
+}
+
+// WithTemplates makes every replacement a text/template,
+// the same as setting Template on each pattern.
+func WithTemplates() Option {
+	return func(r *Replacer) {
+		r.templatesOnly = true
+	}
+}
+
+// WithEnv sets the variables templates refer to as .Env.
+// Defaults to the environment of the process.
+func WithEnv(env map[string]string) Option {
+	return func(r *Replacer) {
+		r.env = env
+	}
+}
+
+// WithDelimiterSpaces makes literal patterns starting with prefix and ending
+// with suffix match spaces and tabs after the prefix and before the suffix too,
+// so that {{name}} matches {{ name }} as well. Such spaced matches are found
+// within a line by a single regular expression, and the text between the
+// spaces is looked up among the patterns.
+func WithDelimiterSpaces(prefix, suffix string) Option {
+	return func(r *Replacer) {
+		r.delimiterSpaces = true
+		r.prefix, r.suffix = prefix, suffix
+	}
 }
 
 // ErrNoMatchesFound is returned if the replacer did not find any text
-//  that need to be replaced.
+// that need to be replaced.
 var ErrNoMatchesFound = fmt.Errorf("no matches found")
 
 // NewReplacer is a constructor for creating Replacer struct.
-// Accepts replacements and initializes state machines with trie
-func NewReplacer(replacements map[string]string) (*Replacer, error) {
-	root := NewNode()
-	for k, v := range replacements {
-		if err := root.Put(k, v); err != nil {
-			return nil, fmt.Errorf("error creating replacer: %v", err)
+// Accepts replacements and builds the Aho-Corasick automaton once,
+// so that every run advances a single state per byte.
+// Replacements are ordered by key to decide their priority.
+func NewReplacer(replacements map[string]string, opts ...Option) (*Replacer, error) {
+	keys := make([]string, 0, len(replacements))
+	for k := range replacements {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	patterns := make([]Pattern, 0, len(keys))
+	for _, k := range keys {
+		patterns = append(patterns, Pattern{Find: k, Replace: replacements[k]})
+	}
+
+	return NewPatternReplacer(patterns, opts...)
+}
+
+// NewPatternReplacer is a constructor for creating Replacer struct
+// from an ordered list of patterns.
+// Earlier patterns have higher priority with the LeftmostFirst policy.
+func NewPatternReplacer(patterns []Pattern, opts ...Option) (*Replacer, error) {
+	// keys are the texts between delimiters of literal patterns by their index
+	keys := make(map[string]int)
+	r := &Replacer{
+		root:      NewNode(),
+		patterns:  make([]Pattern, len(patterns)),
+		templates: make([]*template.Template, len(patterns)),
+		policy:    LeftmostLongest,
+		wordChars: DefaultWordChars,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	for i, p := range patterns {
+		p.WholeWord = p.WholeWord || r.wholeWords
+		r.hasWholeWords = r.hasWholeWords || p.WholeWord
+		p.Template = p.Template || r.templatesOnly
+		r.limits.needsTotals = r.limits.needsTotals || p.Last
+		r.limits.limited = r.limits.limited || p.Limit != Limit{}
+		r.patterns[i] = p
+
+		if p.Template {
+			tmpl, err := parseTemplate(p)
+			if err != nil {
+				return nil, fmt.Errorf("error creating replacer for %q: %v", p.Find, err)
+			}
+			r.templates[i] = tmpl
+			if r.env == nil {
+				r.env = Environ()
+			}
+		}
+
+		if p.Regexp {
+			rp, err := newRegexpPattern(i, r.folding, p)
+			if err != nil {
+				return nil, fmt.Errorf("error creating replacer for %q: %v", p.Find, err)
+			}
+			r.regexps = append(r.regexps, rp)
+			continue
+		}
+
+		// The trie is folded for all patterns or none of them,
+		// and ASCII folding would ignore the case of fewer letters
+		if p.IgnoreCase && r.folding != FoldUnicode {
+			rp, err := newLiteralRegexpPattern(i, p)
+			if err != nil {
+				return nil, fmt.Errorf("error creating replacer for %q: %v", p.Find, err)
+			}
+			r.regexps = append(r.regexps, rp)
+			continue
+		}
+
+		key, maxLen := foldKey(r.folding, p.Find)
+		if err := r.root.put(key, p.Replace, i); err != nil {
+			return nil, fmt.Errorf("error creating replacer for %q: %v", p.Find, err)
 		}
+		if maxLen > r.maxLen {
+			r.maxLen = maxLen
+		}
+		if len(key) > r.maxDepth {
+			r.maxDepth = len(key)
+		}
+
+		if inner, ok := r.delimited(p.Find); ok {
+			key, _ := foldKey(r.folding, inner)
+			if _, exists := keys[key]; !exists {
+				keys[key] = i
+			}
+		}
+	}
+	r.root.BuildLinks()
+
+	if len(keys) > 0 {
+		rp, err := newDelimitedPattern(r.folding, r.prefix, r.suffix, keys)
+		if err != nil {
+			return nil, fmt.Errorf("error creating replacer for delimiters: %v", err)
+		}
+		r.regexps = append(r.regexps, rp)
+	}
+
+	r.limits.needsTotals = r.limits.needsTotals || r.limits.global.Last
+	r.limits.limited = r.limits.limited || r.limits.global != Limit{}
+	r.limits.run = newOccurrences(len(patterns))
+
+	return r, nil
+}
+
+// delimited returns the text between the delimiters of a find text, without
+// spaces and tabs around it. Find texts spanning lines cannot match spaces,
+// as regular expressions are matched within a line.
+func (r *Replacer) delimited(find string) (string, bool) {
+	if !r.delimiterSpaces || r.prefix+r.suffix == "" || len(find) <= len(r.prefix)+len(r.suffix) ||
+		!strings.HasPrefix(find, r.prefix) || !strings.HasSuffix(find, r.suffix) {
+		return "", false
+	}
+
+	inner := strings.Trim(find[len(r.prefix):len(find)-len(r.suffix)], " \t")
+	if inner == "" || strings.Contains(inner, "\n") {
+		return "", false
 	}
 
-	return &Replacer{root}, nil
+	return inner, true
 }
 
 // Replace accepts a reader and writer.
 // Data from reader is copied into writer.
 // While doing so, it replaces all found matches with replace value.
 //
-// It replaces all matches in 2 passes.
+// It replaces all matches in a single streaming pass.
+//
+// Every byte read is passed through the state machines. Bytes are held back
+// only as long as they could still be part of a match, which is at most the
+// length of the longest pattern. Overlapping matches are resolved as per the
+// replacer's MatchPolicy as soon as no later match can overlap with them.
+//
+// If no matches are found, the data is copied as is and ErrNoMatchesFound is returned.
+func (r *Replacer) Replace(reader io.Reader, writer io.Writer) error {
+	return r.ReplaceFile("", reader, writer)
+}
+
+// ReplaceFile is the same as Replace for an input with the given name.
+// The name is available to templated replacements as .File.
 //
-// The first pass is to move all the state machines.
-// Second pass to make use of all the terminal nodes to make the replacements
-// in the writer.
-func (r *Replacer) Replace(reader io.ReadSeeker, writer io.Writer) error {
+// Limits replacing the last occurrence with the PerInput scope need the input
+// to be read twice. Inputs that are not an io.Seeker are read into memory for them.
+func (r *Replacer) ReplaceFile(name string, reader io.Reader, writer io.Writer) error {
 	const bufferSize = 8000
 
-	return r.run(bufferSize, reader, writer)
+	return r.run(bufferSize, name, reader, writer)
+}
+
+// startInput returns where occurrences of the input are counted for limits,
+// which is nil if there are no limits. Occurrences in the PerInput scope
+// are counted from zero for every input.
+func (r *Replacer) startInput(reader io.Reader) (io.Reader, *occurrences, error) {
+	if !r.limits.limited {
+		return reader, nil, nil
+	}
+	if r.limits.scope != PerInput {
+		return reader, r.limits.run, nil
+	}
+
+	o := newOccurrences(len(r.patterns))
+	if !r.limits.needsTotals {
+		return reader, o, nil
+	}
+
+	reader, err := r.countInput(o, reader)
+	return reader, o, err
 }
 
 // ReplaceString accepts an input string and replaces strings
@@ -61,60 +317,13 @@ func (r *Replacer) ReplaceString(in string) (string, error) {
 	return writer.String(), nil
 }
 
-func (r *Replacer) run(bufferSize int, reader io.ReadSeeker, writer io.Writer) error {
-	sm := NewStateMachines(r.root)
-
-	// Construct the state machines first
-	for position, readBuffer := int64(0), make([]byte, bufferSize); true; {
-		n, err := reader.Read(readBuffer)
-		if n > 0 {
-			for _, b := range readBuffer[:n] {
-				sm.Accept(b, position)
-				position++
-			}
-		}
-
-		if err == io.EOF {
-			break
-		}
-
-		if err != nil {
-			return fmt.Errorf("error finding matches: %v", err)
-		}
-	}
-
-	if len(sm.TerminalMachines) == 0 {
-		return ErrNoMatchesFound
-	}
-
-	// Reset to beginning of file
-	if _, err := reader.Seek(0, io.SeekStart); err != nil {
-		return fmt.Errorf("error seeking to start of file: %v", err)
-	}
-
-	// n represents total bytes read from reader
-	var n int64
-	for _, m := range sm.TerminalMachines {
-		// Copy till first match
-		if _, err := io.CopyN(writer, reader, m.StartPosition-n); err != nil {
-			return fmt.Errorf("error copying data from source to destination: %v", err)
-		}
-
-		// Print the replacement string
-		if _, err := writer.Write([]byte(m.ReplaceWith)); err != nil {
-			return fmt.Errorf("error writing replaced strings: %v", err)
-		}
-
-		// Seek to the end position and move on to next match
-		if _, err := reader.Seek(m.EndPosition+1, io.SeekStart); err != nil {
-			return fmt.Errorf("error seeking to next location: %v", err)
-		}
-
-		// Update total bytes read
-		n = m.EndPosition + 1
+func (r *Replacer) run(bufferSize int, name string, reader io.Reader, writer io.Writer) error {
+	reader, o, err := r.startInput(reader)
+	if err != nil {
+		return err
 	}
 
-	// Copy remaining data.
-	_, err := io.Copy(writer, reader)
-	return err
+	s := newStream(r, name, writer)
+	s.occurrences = o
+	return s.run(bufferSize, reader)
 }
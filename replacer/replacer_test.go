@@ -2,8 +2,12 @@ package replacer
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -63,7 +67,7 @@ func TestReplacer_Run(t *testing.T) {
 		writer := &bytes.Buffer{}
 
 		{
-			err := r.run(10, reader, writer)
+			err := r.run(10, "", reader, writer)
 			assert.NoError(t, err)
 		}
 
@@ -71,6 +75,206 @@ func TestReplacer_Run(t *testing.T) {
 	})
 }
 
+// errReader always fails with err on Read
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+func TestReplacer_Streaming(t *testing.T) {
+	replacement := map[string]string{
+		"key1": "value1",
+		"key2": "value2",
+	}
+
+	t.Run("should replace matches spanning across reads from a plain reader", func(t *testing.T) {
+		r, err := NewReplacer(replacement)
+		assert.NoError(t, err)
+
+		reader := iotest.OneByteReader(strings.NewReader("key1key2 and key1"))
+		writer := &bytes.Buffer{}
+
+		assert.NoError(t, r.Replace(reader, writer))
+		assert.Equal(t, "value1value2 and value1", writer.String())
+	})
+
+	t.Run("should copy input as is if there are no matches", func(t *testing.T) {
+		r, err := NewReplacer(replacement)
+		assert.NoError(t, err)
+
+		input := "key3 and key"
+		writer := &bytes.Buffer{}
+
+		assert.Equal(t, ErrNoMatchesFound, r.run(4, "", strings.NewReader(input), writer))
+		assert.Equal(t, input, writer.String())
+	})
+
+	t.Run("should write output before the whole input is read", func(t *testing.T) {
+		r, err := NewReplacer(replacement)
+		assert.NoError(t, err)
+
+		readErr := errors.New("connection reset")
+		reader := io.MultiReader(strings.NewReader("some key1 text ke"), errReader{readErr})
+		writer := &bytes.Buffer{}
+
+		err = r.run(32, "", reader, writer)
+		assert.EqualError(t, err, "error finding matches: connection reset")
+		assert.Equal(t, "some value1 text", writer.String())
+	})
+
+	t.Run("should write runs of overlapping matches before they end", func(t *testing.T) {
+		for _, policy := range []MatchPolicy{LeftmostLongest, LeftmostFirst} {
+			r, err := NewReplacer(map[string]string{"aa": "b"}, WithMatchPolicy(policy))
+			assert.NoError(t, err)
+
+			readErr := errors.New("connection reset")
+			reader := io.MultiReader(strings.NewReader(strings.Repeat("a", 1<<20)), errReader{readErr})
+			writer := &bytes.Buffer{}
+
+			err = r.run(16, "", reader, writer)
+			assert.EqualError(t, err, "error finding matches: connection reset")
+			assert.Equal(t, strings.Repeat("b", 1<<19), writer.String(), policy.String())
+		}
+	})
+}
+
+func TestReplacer_CaseFolding(t *testing.T) {
+	t.Run("should replace keys ignoring ASCII case", func(t *testing.T) {
+		r, err := NewReplacer(map[string]string{"foo": "bar"}, WithCaseFolding(FoldASCII))
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString("Foo, FOO and foo")
+		assert.NoError(t, err)
+		assert.Equal(t, "bar, bar and bar", output)
+	})
+
+	t.Run("should reject keys that are equal after folding", func(t *testing.T) {
+		_, err := NewReplacer(map[string]string{"foo": "bar", "FOO": "baz"}, WithCaseFolding(FoldASCII))
+		assert.EqualError(t, err, `error creating replacer for "foo": conflict: the string already exists`)
+	})
+
+	t.Run("should replace keys ignoring unicode case", func(t *testing.T) {
+		r, err := NewReplacer(map[string]string{"straße": "street", "ok": "fine"}, WithCaseFolding(FoldUnicode))
+		assert.NoError(t, err)
+
+		input := "STRAßE is o\u212a"
+		writer := &bytes.Buffer{}
+
+		assert.NoError(t, r.run(3, "", strings.NewReader(input), writer))
+		assert.Equal(t, "street is fine", writer.String())
+	})
+
+	t.Run("should match case sensitively by default", func(t *testing.T) {
+		r, err := NewReplacer(map[string]string{"foo": "bar"})
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString("Foo")
+		assert.Equal(t, ErrNoMatchesFound, err)
+		assert.Equal(t, "Foo", output)
+	})
+
+	t.Run("should ignore case only for patterns ignoring case", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{
+			{Find: "foo", Replace: "$1bar", IgnoreCase: true},
+			{Find: "Baz", Replace: "qux"},
+			{Find: "v([0-9])", Replace: "version $1", Regexp: true, IgnoreCase: true},
+		})
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString("FOO Foo foo Baz baz V2")
+		assert.NoError(t, err)
+		assert.Equal(t, "$1bar $1bar $1bar qux baz version 2", output)
+	})
+
+	t.Run("should reject patterns ignoring case across lines", func(t *testing.T) {
+		_, err := NewPatternReplacer([]Pattern{{Find: "a\nb", IgnoreCase: true}})
+		assert.Error(t, err)
+
+		_, err = NewPatternReplacer([]Pattern{{Find: "a\nb", IgnoreCase: true}}, WithCaseFolding(FoldUnicode))
+		assert.NoError(t, err)
+	})
+
+	t.Run("should ignore case of all letters with ASCII folding of the replacer", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{
+			{Find: "ärger", Replace: "trouble", IgnoreCase: true},
+			{Find: "öl", Replace: "oil"},
+		}, WithCaseFolding(FoldASCII))
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString("ÄRGER Ärger ÖL öl")
+		assert.NoError(t, err)
+		assert.Equal(t, "trouble trouble ÖL oil", output)
+	})
+}
+
+func TestReplacer_Overlapping(t *testing.T) {
+	replacements := map[string]string{
+		"ab": "X",
+		"bc": "Y",
+		"c":  "Z",
+	}
+
+	t.Run("should resolve overlapping matches leftmost longest by default", func(t *testing.T) {
+		r, err := NewReplacer(replacements)
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString("abc bc")
+		assert.NoError(t, err)
+		assert.Equal(t, "XZ Y", output)
+	})
+
+	t.Run("should replace the longest of keys that are prefixes of each other", func(t *testing.T) {
+		r, err := NewReplacer(map[string]string{
+			"http":  "ftp",
+			"https": "sftp",
+		})
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString("http://a https://b")
+		assert.NoError(t, err)
+		assert.Equal(t, "ftp://a sftp://b", output)
+	})
+
+	t.Run("should prefer earlier pattern among keys that are prefixes of each other", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{
+			{Find: "http", Replace: "ftp"},
+			{Find: "https", Replace: "sftp"},
+		}, WithMatchPolicy(LeftmostFirst))
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString("https://b")
+		assert.NoError(t, err)
+		assert.Equal(t, "ftps://b", output)
+	})
+
+	t.Run("should resolve overlapping matches by pattern order", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{
+			{Find: "bcd", Replace: "1"},
+			{Find: "abc", Replace: "2"},
+		}, WithMatchPolicy(LeftmostFirst))
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString("abcd")
+		assert.NoError(t, err)
+		assert.Equal(t, "2d", output)
+	})
+
+	t.Run("should resolve overlapping matches by longest overall", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{
+			{Find: "ab", Replace: "1"},
+			{Find: "bcd", Replace: "2"},
+		}, WithMatchPolicy(LongestOverall))
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString("abcd")
+		assert.NoError(t, err)
+		assert.Equal(t, "a2", output)
+	})
+}
+
 func TestReplacer_ReplaceString(t *testing.T) {
 	t.Run("should replace given string and return string when matches are found", func(t *testing.T) {
 		replacement := map[string]string{
@@ -111,3 +315,56 @@ func TestReplacer_ReplaceString(t *testing.T) {
 		}
 	})
 }
+
+func TestReplacer_DelimiterSpaces(t *testing.T) {
+	t.Run("should match spaces and tabs inside delimiters of literal patterns", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{
+			{Find: "{{name}}", Replace: "world"},
+			{Find: "{{names}}", Replace: "people"},
+			{Find: "{{ who }}", Replace: "{{.Match}}!", Template: true},
+			{Find: "name", Replace: "id"},
+		}, WithDelimiterSpaces("{{", "}}"))
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString("{{name}} {{ name }} {{\tnames  }} {{ {{ who}} {{ nome }} name")
+		assert.NoError(t, err)
+		assert.Equal(t, "world world people {{ {{ who}}! {{ nome }} id", output)
+	})
+
+	t.Run("should apply limits of patterns to spaced matches", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{
+			{Find: "<name>", Replace: "world", Limit: Limit{Nth: 2}},
+		}, WithDelimiterSpaces("<", ">"))
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString("< name > <name> < name>")
+		assert.NoError(t, err)
+		assert.Equal(t, "< name > world < name>", output)
+	})
+
+	t.Run("should fold case of spaced matches", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{
+			{Find: "@Name", Replace: "world"},
+		}, WithDelimiterSpaces("@", ""), WithCaseFolding(FoldASCII))
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString("@ NAME @name  @nAmE")
+		assert.NoError(t, err)
+		assert.Equal(t, "world world  world", output)
+	})
+
+	t.Run("should find spaced matches with a single regular expression", func(t *testing.T) {
+		patterns := make([]Pattern, 1000)
+		for i := range patterns {
+			patterns[i] = Pattern{Find: fmt.Sprintf("{{key%d}}", i), Replace: fmt.Sprintf("value%d", i)}
+		}
+
+		r, err := NewPatternReplacer(patterns, WithDelimiterSpaces("{{", "}}"))
+		assert.NoError(t, err)
+		assert.Len(t, r.regexps, 1)
+
+		output, err := r.ReplaceString("{{ key7 }} {{key999}} {{ key1000 }}")
+		assert.NoError(t, err)
+		assert.Equal(t, "value7 value999 {{ key1000 }}", output)
+	})
+}
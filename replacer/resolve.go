@@ -0,0 +1,140 @@
+package replacer
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MatchPolicy decides which match wins when two matches overlap.
+type MatchPolicy int
+
+const (
+	// LeftmostLongest picks the match starting first in the input.
+	// Among matches starting at the same position the longest one wins.
+	LeftmostLongest MatchPolicy = iota
+	// LeftmostFirst picks the match starting first in the input.
+	// Among matches starting at the same position the earliest pattern wins.
+	LeftmostFirst
+	// LongestOverall picks the longest match anywhere in the input.
+	// Ties are broken by picking the leftmost match.
+	// A run of overlapping matches is held in memory till it ends,
+	// as a longer match can still be found anywhere in it.
+	LongestOverall
+)
+
+var matchPolicyNames = map[MatchPolicy]string{
+	LeftmostLongest: "leftmost-longest",
+	LeftmostFirst:   "leftmost-first",
+	LongestOverall:  "longest",
+}
+
+// String returns the name of the policy as accepted by ParseMatchPolicy
+func (p MatchPolicy) String() string {
+	if name, ok := matchPolicyNames[p]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("MatchPolicy(%d)", int(p))
+}
+
+// ParseMatchPolicy returns the policy for the given name.
+// Valid names are leftmost-longest, leftmost-first and longest.
+func ParseMatchPolicy(name string) (MatchPolicy, error) {
+	for policy, policyName := range matchPolicyNames {
+		if policyName == name {
+			return policy, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown match policy %q", name)
+}
+
+// resolve selects non-overlapping matches from candidates as per the policy.
+// The selected matches are returned sorted by StartPosition.
+func resolve(policy MatchPolicy, candidates []*StateMachine) []*StateMachine {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	sorted := make([]*StateMachine, len(candidates))
+	copy(sorted, candidates)
+
+	switch policy {
+	case LeftmostFirst:
+		sort.SliceStable(sorted, func(i, j int) bool {
+			a, b := sorted[i], sorted[j]
+			if a.StartPosition != b.StartPosition {
+				return a.StartPosition < b.StartPosition
+			}
+			if a.Index != b.Index {
+				return a.Index < b.Index
+			}
+			return a.EndPosition > b.EndPosition
+		})
+		return resolveLeftmost(sorted)
+	case LongestOverall:
+		sort.SliceStable(sorted, func(i, j int) bool {
+			a, b := sorted[i], sorted[j]
+			if length(a) != length(b) {
+				return length(a) > length(b)
+			}
+			if a.StartPosition != b.StartPosition {
+				return a.StartPosition < b.StartPosition
+			}
+			return a.Index < b.Index
+		})
+		return resolveLongest(sorted)
+	default:
+		sort.SliceStable(sorted, func(i, j int) bool {
+			a, b := sorted[i], sorted[j]
+			if a.StartPosition != b.StartPosition {
+				return a.StartPosition < b.StartPosition
+			}
+			if a.EndPosition != b.EndPosition {
+				return a.EndPosition > b.EndPosition
+			}
+			return a.Index < b.Index
+		})
+		return resolveLeftmost(sorted)
+	}
+}
+
+// resolveLeftmost expects candidates sorted by StartPosition with the
+// preferred match first for a given position.
+func resolveLeftmost(sorted []*StateMachine) []*StateMachine {
+	selected := make([]*StateMachine, 0, len(sorted))
+	lastEnd := int64(-1)
+	for _, m := range sorted {
+		if m.StartPosition > lastEnd {
+			selected = append(selected, m)
+			lastEnd = m.EndPosition
+		}
+	}
+
+	return selected
+}
+
+// resolveLongest expects candidates sorted by preference.
+// Every candidate not overlapping an already selected match is selected.
+func resolveLongest(sorted []*StateMachine) []*StateMachine {
+	selected := make([]*StateMachine, 0, len(sorted))
+	for _, m := range sorted {
+		// First selected match that ends at or after the start of m
+		i := sort.Search(len(selected), func(i int) bool {
+			return selected[i].EndPosition >= m.StartPosition
+		})
+		if i < len(selected) && selected[i].StartPosition <= m.EndPosition {
+			continue
+		}
+
+		selected = append(selected, nil)
+		copy(selected[i+1:], selected[i:])
+		selected[i] = m
+	}
+
+	return selected
+}
+
+func length(m *StateMachine) int64 {
+	return m.EndPosition - m.StartPosition + 1
+}
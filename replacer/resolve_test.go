@@ -0,0 +1,61 @@
+package replacer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	// Candidates found in "abcd" for patterns ["bcd", "ab", "abc", "cd"]
+	candidates := func() []*StateMachine {
+		return []*StateMachine{
+			{StartPosition: 0, EndPosition: 1, Index: 1},
+			{StartPosition: 0, EndPosition: 2, Index: 2},
+			{StartPosition: 1, EndPosition: 3, Index: 0},
+			{StartPosition: 2, EndPosition: 3, Index: 3},
+		}
+	}
+
+	spans := func(matches []*StateMachine) [][2]int64 {
+		result := make([][2]int64, 0, len(matches))
+		for _, m := range matches {
+			result = append(result, [2]int64{m.StartPosition, m.EndPosition})
+		}
+		return result
+	}
+
+	t.Run("should pick leftmost longest matches", func(t *testing.T) {
+		assert.Equal(t, [][2]int64{{0, 2}}, spans(resolve(LeftmostLongest, candidates())))
+	})
+
+	t.Run("should pick leftmost matches by pattern order", func(t *testing.T) {
+		assert.Equal(t, [][2]int64{{0, 1}, {2, 3}}, spans(resolve(LeftmostFirst, candidates())))
+	})
+
+	t.Run("should pick longest matches overall", func(t *testing.T) {
+		matches := []*StateMachine{
+			{StartPosition: 0, EndPosition: 1, Index: 0},
+			{StartPosition: 1, EndPosition: 3, Index: 1},
+			{StartPosition: 3, EndPosition: 4, Index: 2},
+			{StartPosition: 5, EndPosition: 5, Index: 3},
+		}
+
+		assert.Equal(t, [][2]int64{{1, 3}, {5, 5}}, spans(resolve(LongestOverall, matches)))
+	})
+
+	t.Run("should return nothing for no candidates", func(t *testing.T) {
+		assert.Empty(t, resolve(LeftmostLongest, nil))
+	})
+}
+
+func TestParseMatchPolicy(t *testing.T) {
+	for _, policy := range []MatchPolicy{LeftmostLongest, LeftmostFirst, LongestOverall} {
+		parsed, err := ParseMatchPolicy(policy.String())
+		assert.NoError(t, err)
+		assert.Equal(t, policy, parsed)
+	}
+
+	_, err := ParseMatchPolicy("shortest")
+	assert.EqualError(t, err, `unknown match policy "shortest"`)
+}
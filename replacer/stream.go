@@ -0,0 +1,473 @@
+package replacer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// stream holds the state of a single run of the replacer.
+// Bytes are held in pending till no match can start in them anymore.
+type stream struct {
+	replacer *Replacer
+	// writer buffers the output, which is flushed once the run ends
+	writer *bufio.Writer
+	sm     *StateMachines
+	// name is the name of the input
+	name string
+	// counting streams only count matches for limits
+	counting bool
+	// occurrences counts occurrences for limits, unless there are none
+	occurrences *occurrences
+	// recording streams collect the replacements written as edits
+	recording bool
+	edits     []Edit
+
+	// pending holds bytes that are read but not written yet.
+	// pendingStart is the position of the first byte in pending.
+	pending      []byte
+	pendingStart int64
+	// history holds the last bytes before pendingStart
+	// to find word boundaries of matches at the start of pending
+	history    [utf8.UTFMax]byte
+	historyLen int
+	// line and column are the zero based position of pendingStart
+	line   int
+	column int
+	// position is the position of the next byte to be passed to the state machines
+	position int64
+	// fed is the number of bytes passed to the state machines.
+	// It differs from position only when folding changes the length of the input,
+	// in which case offsets maps the last fed bytes to the span of input they came from.
+	fed     int64
+	offsets []span
+
+	// lineStart is the position of the first byte of the line not matched
+	// by regular expressions yet, and scanned is the position till which
+	// the line is searched for its end.
+	lineStart int64
+	scanned   int64
+
+	// candidates are matches that are not resolved yet.
+	// The first sorted of them are sorted by StartPosition.
+	candidates []*StateMachine
+	sorted     int
+	// replaced is the number of replacements written so far
+	replaced int
+}
+
+// run reads the whole input and replaces the matches in it.
+// Output written before an error is flushed as well.
+func (s *stream) run(bufferSize int, reader io.Reader) error {
+	err := s.replaceAll(bufferSize, reader)
+	if flushErr := s.writer.Flush(); err == nil && flushErr != nil {
+		return fmt.Errorf("error writing replaced strings: %v", flushErr)
+	}
+
+	return err
+}
+
+func (s *stream) replaceAll(bufferSize int, reader io.Reader) error {
+	for readBuffer := make([]byte, bufferSize); true; {
+		n, err := reader.Read(readBuffer)
+		if n > 0 {
+			s.accept(readBuffer[:n])
+			if err := s.settle(s.horizon()); err != nil {
+				return err
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("error finding matches: %v", err)
+		}
+	}
+
+	// Nothing more can be matched, settle everything that is pending
+	s.finish()
+	if err := s.settle(s.position + 1); err != nil {
+		return err
+	}
+
+	if s.replaced == 0 {
+		return ErrNoMatchesFound
+	}
+
+	return nil
+}
+
+// span is an inclusive range of positions in the input
+type span struct {
+	start, end int64
+}
+
+func newStream(r *Replacer, name string, writer io.Writer) *stream {
+	s := &stream{
+		replacer: r,
+		writer:   bufio.NewWriter(writer),
+		sm:       NewStateMachines(r.root),
+		name:     name,
+	}
+	if r.folding == FoldUnicode {
+		s.offsets = make([]span, r.maxDepth+1)
+	}
+
+	return s
+}
+
+// accept passes the data through the state machines
+// and collects the matches found as candidates.
+func (s *stream) accept(data []byte) {
+	s.pending = append(s.pending, data...)
+
+	switch s.replacer.folding {
+	case FoldUnicode:
+		s.acceptRunes(false)
+	case FoldASCII:
+		for _, b := range data {
+			s.feed(foldByte(b), s.position, s.position)
+			s.position++
+		}
+	default:
+		for _, b := range data {
+			s.feed(b, s.position, s.position)
+			s.position++
+		}
+	}
+
+	if len(s.replacer.regexps) > 0 {
+		s.matchLines(false)
+	}
+}
+
+// finish passes any bytes held back by accept through the state machines.
+// It must be called once the input is exhausted.
+func (s *stream) finish() {
+	if s.replacer.folding == FoldUnicode {
+		s.acceptRunes(true)
+	}
+
+	if len(s.replacer.regexps) > 0 {
+		s.matchLines(true)
+	}
+}
+
+// acceptRunes decodes pending bytes that are not passed through the state machines yet
+// and passes their case folded encoding. A rune split across reads is held back,
+// unless the input is exhausted.
+func (s *stream) acceptRunes(eof bool) {
+	var encoded [utf8.UTFMax]byte
+	for {
+		unread := s.pending[s.position-s.pendingStart:]
+		if len(unread) == 0 || (!eof && !utf8.FullRune(unread)) {
+			return
+		}
+
+		r, size := utf8.DecodeRune(unread)
+		end := s.position + int64(size) - 1
+		if r == utf8.RuneError && size == 1 {
+			s.feed(unread[0], s.position, end)
+		} else {
+			n := utf8.EncodeRune(encoded[:], foldRune(r))
+			for _, b := range encoded[:n] {
+				s.feed(b, s.position, end)
+			}
+		}
+		s.position += int64(size)
+	}
+}
+
+// feed passes a single byte through the state machines.
+// The byte was folded from the input found between start and end.
+func (s *stream) feed(b byte, start, end int64) {
+	if s.offsets != nil {
+		s.offsets[s.fed%int64(len(s.offsets))] = span{start, end}
+	}
+
+	s.sm.Accept(b, s.fed)
+	s.fed++
+
+	if len(s.sm.TerminalMachines) == 0 {
+		return
+	}
+
+	for _, m := range s.sm.TerminalMachines {
+		if s.offsets != nil {
+			m.StartPosition = s.offsets[m.StartPosition%int64(len(s.offsets))].start
+			m.EndPosition = s.offsets[m.EndPosition%int64(len(s.offsets))].end
+		}
+		s.candidates = append(s.candidates, m)
+	}
+	s.sm.TerminalMachines = s.sm.TerminalMachines[:0]
+}
+
+// horizon returns the earliest position a match can start at,
+// when matches for all bytes before position are already found.
+// Every match starting before it is found as well.
+// Whole word matches are settled only once the rune after them is read,
+// and regular expressions only once the line is complete.
+func (s *stream) horizon() int64 {
+	r := s.replacer
+	horizon := s.position - int64(r.maxLen) + 1
+	if r.hasWholeWords {
+		horizon -= utf8.UTFMax
+	}
+	if len(r.regexps) > 0 && s.lineStart < horizon {
+		horizon = s.lineStart
+	}
+
+	return horizon
+}
+
+// settle resolves and writes the candidates that no future match can overlap
+// with or win against, as horizon is the earliest position a future match can
+// start at. Bytes before horizon that are not part of any candidate are written as is.
+func (s *stream) settle(horizon int64) error {
+	s.sortCandidates()
+
+	var err error
+	if s.replacer.policy == LongestOverall {
+		err = s.settleGroups(horizon)
+	} else {
+		err = s.settleLeftmost(horizon)
+	}
+	if err != nil {
+		return err
+	}
+
+	safe := horizon
+	if len(s.candidates) > 0 && s.candidates[0].StartPosition < safe {
+		safe = s.candidates[0].StartPosition
+	}
+	if safe > s.position {
+		safe = s.position
+	}
+
+	return s.flush(safe)
+}
+
+// sortCandidates sorts the candidates found since the last call
+// and merges them into the sorted ones.
+func (s *stream) sortCandidates() {
+	added := s.candidates[s.sorted:]
+	sort.SliceStable(added, func(i, j int) bool {
+		return added[i].StartPosition < added[j].StartPosition
+	})
+
+	if s.sorted > 0 && len(added) > 0 && added[0].StartPosition < s.candidates[s.sorted-1].StartPosition {
+		merged := make([]*StateMachine, 0, len(s.candidates))
+		earlier := s.candidates[:s.sorted]
+		for len(earlier) > 0 && len(added) > 0 {
+			if added[0].StartPosition < earlier[0].StartPosition {
+				merged, added = append(merged, added[0]), added[1:]
+			} else {
+				merged, earlier = append(merged, earlier[0]), earlier[1:]
+			}
+		}
+		merged = append(append(merged, earlier...), added...)
+		s.candidates = merged
+	}
+
+	s.sorted = len(s.candidates)
+}
+
+// settleLeftmost writes the preferred candidate of every position before horizon,
+// as every match starting there is already found. Candidates overlapping
+// a written match are dropped. Only candidates starting at or after
+// horizon are kept, so memory is bounded by the length of the patterns.
+func (s *stream) settleLeftmost(horizon int64) error {
+	settled := 0
+	for settled < len(s.candidates) && s.candidates[settled].StartPosition < horizon {
+		start := s.candidates[settled].StartPosition
+		next := settled + 1
+		for next < len(s.candidates) && s.candidates[next].StartPosition == start {
+			next++
+		}
+
+		// Candidates overlapping an earlier match are already written
+		group := s.candidates[settled:next]
+		settled = next
+		if start < s.pendingStart {
+			continue
+		}
+
+		if s.replacer.hasWholeWords {
+			group = s.filterWholeWords(group)
+		}
+		if len(group) == 0 {
+			continue
+		}
+
+		if err := s.write(s.preferred(group)); err != nil {
+			return err
+		}
+	}
+	s.candidates = s.candidates[settled:]
+	s.sorted -= settled
+
+	return nil
+}
+
+// preferred returns the candidate picked by the leftmost policies
+// among candidates starting at the same position
+func (s *stream) preferred(group []*StateMachine) *StateMachine {
+	best := group[0]
+	for _, m := range group[1:] {
+		longer, earlier := m.EndPosition > best.EndPosition, m.Index < best.Index
+		if s.replacer.policy == LeftmostFirst {
+			if earlier || (m.Index == best.Index && longer) {
+				best = m
+			}
+		} else if longer || (m.EndPosition == best.EndPosition && earlier) {
+			best = m
+		}
+	}
+
+	return best
+}
+
+// settleGroups resolves and writes every group of overlapping candidates
+// that ends before horizon, as a longer match can win against
+// any candidate it overlaps with.
+func (s *stream) settleGroups(horizon int64) error {
+	settled := 0
+	for settled < len(s.candidates) {
+		// Find the group of candidates overlapping each other
+		groupEnd := s.candidates[settled].EndPosition
+		next := settled + 1
+		for ; next < len(s.candidates) && s.candidates[next].StartPosition <= groupEnd; next++ {
+			if s.candidates[next].EndPosition > groupEnd {
+				groupEnd = s.candidates[next].EndPosition
+			}
+		}
+
+		// A later match can still overlap with this group
+		if groupEnd >= horizon {
+			break
+		}
+
+		group := s.candidates[settled:next]
+		if s.replacer.hasWholeWords {
+			group = s.filterWholeWords(group)
+		}
+
+		for _, m := range resolve(s.replacer.policy, group) {
+			if err := s.write(m); err != nil {
+				return err
+			}
+		}
+		settled = next
+	}
+	s.candidates = s.candidates[settled:]
+	s.sorted -= settled
+
+	return nil
+}
+
+// filterWholeWords drops candidates of whole word patterns
+// that are not surrounded by word boundaries.
+func (s *stream) filterWholeWords(group []*StateMachine) []*StateMachine {
+	filtered := make([]*StateMachine, 0, len(group))
+	for _, m := range group {
+		if s.replacer.patterns[m.Index].WholeWord && !s.atWordBoundary(m) {
+			continue
+		}
+		filtered = append(filtered, m)
+	}
+
+	return filtered
+}
+
+// write copies pending bytes till the start of the match
+// and writes the replacement in place of the matched bytes.
+// Matches not allowed by limits are copied as they are.
+func (s *stream) write(m *StateMachine) error {
+	if s.counting {
+		s.occurrences.total(m.Index)
+		s.replaced++
+		return s.flush(m.EndPosition + 1)
+	}
+
+	if s.occurrences != nil && !s.occurrences.allow(s.replacer.limits.global, s.replacer.patterns[m.Index].Limit, m.Index) {
+		return s.flush(m.EndPosition + 1)
+	}
+
+	if err := s.flush(m.StartPosition); err != nil {
+		return err
+	}
+
+	replacement := m.ReplaceWith
+	if tmpl := s.replacer.templates[m.Index]; tmpl != nil {
+		var rendered strings.Builder
+		if err := s.render(&rendered, tmpl, m); err != nil {
+			if message, failed := failure(err); failed {
+				return fmt.Errorf("%s:%d: %s", s.name, s.line+1, message)
+			}
+			return fmt.Errorf("error rendering replacement: %v", err)
+		}
+		replacement = rendered.String()
+	}
+
+	if s.recording {
+		s.edits = append(s.edits, Edit{
+			Start:       m.StartPosition,
+			End:         m.EndPosition + 1,
+			Replacement: replacement,
+		})
+	}
+
+	if _, err := io.WriteString(s.writer, replacement); err != nil {
+		return fmt.Errorf("error writing replaced strings: %v", err)
+	}
+	s.replaced++
+
+	s.discard(m.EndPosition + 1)
+	return nil
+}
+
+// flush writes pending bytes till the given position
+func (s *stream) flush(till int64) error {
+	if till <= s.pendingStart {
+		return nil
+	}
+
+	n := till - s.pendingStart
+	if _, err := s.writer.Write(s.pending[:n]); err != nil {
+		return fmt.Errorf("error copying data from source to destination: %v", err)
+	}
+
+	s.discard(till)
+	return nil
+}
+
+// discard drops pending bytes till the given position without writing them
+func (s *stream) discard(till int64) {
+	dropped := s.pending[:till-s.pendingStart]
+	if len(dropped) >= utf8.UTFMax {
+		s.historyLen = copy(s.history[:], dropped[len(dropped)-utf8.UTFMax:])
+	} else if len(dropped) > 0 {
+		keep := utf8.UTFMax - len(dropped)
+		if keep > s.historyLen {
+			keep = s.historyLen
+		}
+		copy(s.history[:], s.history[s.historyLen-keep:s.historyLen])
+		s.historyLen = keep + copy(s.history[keep:], dropped)
+	}
+
+	if newlines := bytes.Count(dropped, []byte{'\n'}); newlines > 0 {
+		s.line += newlines
+		s.column = len(dropped) - bytes.LastIndexByte(dropped, '\n') - 1
+	} else {
+		s.column += len(dropped)
+	}
+
+	s.pending = s.pending[till-s.pendingStart:]
+	s.pendingStart = till
+}
@@ -0,0 +1,89 @@
+package replacer
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// MatchContext is the data available to templated replacements.
+type MatchContext struct {
+	// Match is the matched text
+	Match string
+	// Groups holds the match followed by its submatches for regular expression patterns
+	Groups []string
+	// File is the name of the input given to ReplaceFile
+	File string
+	// Line and Column are the position of the start of the match in the input.
+	// Both start at 1, and Column is counted in bytes.
+	Line   int
+	Column int
+	// Index is the number of the replacement in the input, starting at 1
+	Index int
+	// Env holds the environment of the process when the replacer was created,
+	// or the variables set with WithEnv
+	Env map[string]string
+}
+
+// templateFuncs are the functions available to templated replacements.
+// fail stops replacing with the given message as the error.
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	"fail":  fail,
+}
+
+func fail(message string) (string, error) {
+	return "", errors.New(message)
+}
+
+// failCall precedes the message of fail in errors of executing templates
+const failCall = "error calling fail: "
+
+// failure returns the message given to fail if it caused the error
+func failure(err error) (string, bool) {
+	i := strings.LastIndex(err.Error(), failCall)
+	if i < 0 {
+		return "", false
+	}
+
+	return err.Error()[i+len(failCall):], true
+}
+
+// parseTemplate parses the replacement of the pattern as a text/template.
+// Referring to a missing key of .Env is an error while rendering.
+func parseTemplate(p Pattern) (*template.Template, error) {
+	return template.New(p.Find).
+		Funcs(templateFuncs).
+		Option("missingkey=error").
+		Parse(p.Replace)
+}
+
+// Environ returns the environment of the process as a map
+func Environ() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if i := strings.IndexByte(kv, '='); i > 0 {
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+
+	return env
+}
+
+// render executes the template of the matched pattern.
+// The match must be at the start of pending.
+func (s *stream) render(writer io.Writer, tmpl *template.Template, m *StateMachine) error {
+	return tmpl.Execute(writer, MatchContext{
+		Match:  string(s.pending[:m.EndPosition-m.StartPosition+1]),
+		Groups: m.Groups,
+		File:   s.name,
+		Line:   s.line + 1,
+		Column: s.column + 1,
+		Index:  s.replaced + 1,
+		Env:    s.replacer.env,
+	})
+}
@@ -0,0 +1,72 @@
+package replacer
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplacer_Templates(t *testing.T) {
+	t.Run("should render templates with the match context", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{
+			{Find: "todo", Replace: "{{.Match | upper}}@{{.File}}:{{.Line}}:{{.Column}}#{{.Index}}", Template: true},
+			{Find: "key", Replace: "{{.Match}}"},
+		})
+		assert.NoError(t, err)
+
+		input := "a todo\nkey and\n  todo"
+		writer := &bytes.Buffer{}
+
+		assert.NoError(t, r.ReplaceFile("notes.txt", strings.NewReader(input), writer))
+		assert.Equal(t, "a TODO@notes.txt:1:3#1\n{{.Match}} and\n  TODO@notes.txt:3:3#3", writer.String())
+	})
+
+	t.Run("should render groups of regular expressions and environment", func(t *testing.T) {
+		assert.NoError(t, os.Setenv("REPLACE_TEXT_TEST_SUFFIX", "-new"))
+		defer os.Unsetenv("REPLACE_TEXT_TEST_SUFFIX")
+
+		r, err := NewPatternReplacer([]Pattern{
+			{Find: `(\w+)_id`, Replace: `{{index .Groups 1}}{{.Env.REPLACE_TEXT_TEST_SUFFIX}}`, Regexp: true},
+		}, WithTemplates())
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString("user_id")
+		assert.NoError(t, err)
+		assert.Equal(t, "user-new", output)
+	})
+
+	t.Run("should render variables set with WithEnv as environment", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{
+			{Find: "host", Replace: `{{.Env.HOST}}`, Template: true},
+		}, WithEnv(map[string]string{"HOST": "example.com"}))
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString("http://host/")
+		assert.NoError(t, err)
+		assert.Equal(t, "http://example.com/", output)
+	})
+
+	t.Run("should return error for invalid templates while creating replacer", func(t *testing.T) {
+		_, err := NewPatternReplacer([]Pattern{{Find: "a", Replace: "{{.Match", Template: true}})
+		assert.Error(t, err)
+	})
+
+	t.Run("should return error for templates failing to render", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{{Find: "a", Replace: "{{.Env.REPLACE_TEXT_TEST_MISSING}}", Template: true}})
+		assert.NoError(t, err)
+
+		_, err = r.ReplaceString("a")
+		assert.Error(t, err)
+	})
+
+	t.Run("should stop replacing with the message of fail", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{{Find: "a", Replace: `{{fail "a is not allowed"}}`, Template: true}})
+		assert.NoError(t, err)
+
+		err = r.ReplaceFile("notes.txt", strings.NewReader("b\na"), &bytes.Buffer{})
+		assert.EqualError(t, err, "notes.txt:2: a is not allowed")
+	})
+}
@@ -0,0 +1,56 @@
+package replacer
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// WordChars reports whether a rune is part of a word.
+// It decides the boundaries of whole word matches.
+type WordChars func(r rune) bool
+
+// DefaultWordChars treats ASCII letters, digits and underscore as word characters.
+// This is the same set as \w in regular expressions.
+func DefaultWordChars(r rune) bool {
+	return r == '_' ||
+		('0' <= r && r <= '9') ||
+		('a' <= r && r <= 'z') ||
+		('A' <= r && r <= 'Z')
+}
+
+// IdentifierWordChars treats characters of identifiers in languages
+// like Javascript as word characters. It is DefaultWordChars along with '$'.
+func IdentifierWordChars(r rune) bool {
+	return r == '$' || DefaultWordChars(r)
+}
+
+// WordCharsOf returns WordChars that treats only the runes in set as word characters.
+func WordCharsOf(set string) WordChars {
+	return func(r rune) bool {
+		return strings.ContainsRune(set, r)
+	}
+}
+
+// atWordBoundary returns true if the runes before and after the match
+// are not word characters. Start and end of the input are boundaries as well.
+func (s *stream) atWordBoundary(m *StateMachine) bool {
+	isWordChar := s.replacer.wordChars
+
+	before := s.pending[:m.StartPosition-s.pendingStart]
+	if len(before) < utf8.UTFMax {
+		var buf [2 * utf8.UTFMax]byte
+		n := copy(buf[:], s.history[:s.historyLen])
+		n += copy(buf[n:], before)
+		before = buf[:n]
+	}
+	if r, size := utf8.DecodeLastRune(before); size > 0 && isWordChar(r) {
+		return false
+	}
+
+	after := s.pending[m.EndPosition+1-s.pendingStart:]
+	if r, size := utf8.DecodeRune(after); size > 0 && isWordChar(r) {
+		return false
+	}
+
+	return true
+}
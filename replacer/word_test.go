@@ -0,0 +1,66 @@
+package replacer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplacer_WholeWords(t *testing.T) {
+	input := "id valid idle (id) $id xid id"
+
+	t.Run("should replace only whole words of whole word patterns", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{
+			{Find: "id", Replace: "identifier", WholeWord: true},
+			{Find: "le", Replace: "LE"},
+		})
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString(input)
+		assert.NoError(t, err)
+		assert.Equal(t, "identifier valid idLE (identifier) $identifier xid identifier", output)
+	})
+
+	t.Run("should replace only whole words of every pattern globally", func(t *testing.T) {
+		r, err := NewPatternReplacer([]Pattern{
+			{Find: "id", Replace: "identifier"},
+			{Find: "le", Replace: "LE"},
+		}, WithWholeWords())
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString(input)
+		assert.NoError(t, err)
+		assert.Equal(t, "identifier valid idle (identifier) $identifier xid identifier", output)
+	})
+
+	t.Run("should find boundaries across reads", func(t *testing.T) {
+		r, err := NewReplacer(map[string]string{"id": "identifier"}, WithWholeWords())
+		assert.NoError(t, err)
+
+		for _, bufferSize := range []int{1, 2, 3, 7} {
+			writer := &bytes.Buffer{}
+			assert.NoError(t, r.run(bufferSize, "", strings.NewReader(input), writer))
+			assert.Equal(t, "identifier valid idle (identifier) $identifier xid identifier", writer.String())
+		}
+	})
+
+	t.Run("should use identifier word characters", func(t *testing.T) {
+		r, err := NewReplacer(map[string]string{"id": "identifier"}, WithWholeWords(), WithWordChars(IdentifierWordChars))
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString(input)
+		assert.NoError(t, err)
+		assert.Equal(t, "identifier valid idle (identifier) $id xid identifier", output)
+	})
+
+	t.Run("should use given word characters", func(t *testing.T) {
+		r, err := NewReplacer(map[string]string{"id": "identifier"}, WithWholeWords(), WithWordChars(WordCharsOf("(é")))
+		assert.NoError(t, err)
+
+		output, err := r.ReplaceString("éid (id) xid")
+		assert.NoError(t, err)
+		assert.Equal(t, "éid (id) xidentifier", output)
+	})
+}
@@ -0,0 +1,151 @@
+// Package walk lists the files of a directory tree on an fs.Fs
+package walk
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aswinkarthik/replace-text/fs"
+	"github.com/aswinkarthik/replace-text/glob"
+	"github.com/aswinkarthik/replace-text/ignore"
+)
+
+// Options decide which entries of a directory tree are visited
+type Options struct {
+	// Include limits the files to those matching any of the globs.
+	// All files are included if it is empty.
+	Include []*glob.Glob
+
+	// Exclude skips files and directories matching any of the globs.
+	Exclude []*glob.Glob
+
+	// MaxDepth limits how deep the walk descends below the root.
+	// Files directly in the root are at depth 1. Zero means no limit.
+	MaxDepth int
+
+	// Hidden includes files and directories whose name starts with a dot
+	Hidden bool
+
+	// Ignore skips files and directories ignored by the ignore files
+	// found while walking, and the .git directory itself.
+	Ignore bool
+}
+
+// Walk calls fn for every file below root that is selected by the options,
+// in lexical order. Globs are matched against the slash separated path
+// relative to root. Symbolic links are not followed.
+func Walk(fsys fs.Fs, root string, opts Options, fn func(path string) error) error {
+	return opts.walk(fsys, root, "", 1, nil, fn)
+}
+
+func (o Options) walk(fsys fs.Fs, dir, rel string, depth int, ignores ignore.Stack, fn func(path string) error) error {
+	if o.MaxDepth > 0 && depth > o.MaxDepth {
+		return nil
+	}
+
+	infos, err := fsys.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("error reading directory %s: %v", dir, err)
+	}
+
+	if o.Ignore {
+		matchers, err := loadIgnores(fsys, dir, rel)
+		if err != nil {
+			return err
+		}
+		// Copy on append, so that sibling directories do not share matchers
+		ignores = append(ignores[:len(ignores):len(ignores)], matchers...)
+	}
+
+	for _, info := range infos {
+		name := info.Name()
+		if !o.Hidden && strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+
+		entryPath := filepath.Join(dir, name)
+		entryRel := path.Join(rel, name)
+
+		if o.Ignore && (name == ".git" || ignores.Ignored(entryRel, info.IsDir())) {
+			continue
+		}
+
+		if info.IsDir() {
+			if o.excluded(entryRel) || o.excluded(entryRel+"/") {
+				continue
+			}
+
+			if err := o.walk(fsys, entryPath, entryRel, depth+1, ignores, fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !info.Mode().IsRegular() || o.excluded(entryRel) || !o.included(entryRel) {
+			continue
+		}
+
+		if err := fn(entryPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadIgnores reads the ignore files of the directory dir,
+// in the increasing order of their precedence
+func loadIgnores(fsys fs.Fs, dir, rel string) (ignore.Stack, error) {
+	var matchers ignore.Stack
+	for _, name := range append([]string{ignore.GitExclude}, ignore.FileNames...) {
+		ignorePath := filepath.Join(dir, filepath.FromSlash(name))
+		if !fsys.IsFile(ignorePath) {
+			continue
+		}
+
+		file, err := fsys.Open(ignorePath)
+		if err != nil {
+			return nil, fmt.Errorf("error opening ignore file: %v", err)
+		}
+
+		m, err := ignore.Parse(file, rel)
+		_ = file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading ignore file %s: %v", ignorePath, err)
+		}
+		matchers = append(matchers, m)
+	}
+
+	return matchers, nil
+}
+
+func (o Options) included(rel string) bool {
+	if len(o.Include) == 0 {
+		return true
+	}
+
+	for _, g := range o.Include {
+		if g.Match(rel) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (o Options) excluded(rel string) bool {
+	for _, g := range o.Exclude {
+		if g.Match(rel) {
+			return true
+		}
+	}
+
+	return false
+}
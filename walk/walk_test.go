@@ -0,0 +1,151 @@
+package walk_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/aswinkarthik/replace-text/fs"
+	"github.com/aswinkarthik/replace-text/glob"
+	"github.com/aswinkarthik/replace-text/walk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWalk(t *testing.T) {
+	memFs := fs.NewMemFs()
+	for _, path := range []string{
+		"src/main.go",
+		"src/README.md",
+		"src/.env",
+		"src/.git/config",
+		"src/cmd/app/app.go",
+		"src/vendor/lib/lib.go",
+	} {
+		assert.NoError(t, memFs.MkdirAll(filepath.Dir(path), 0755))
+		file, err := memFs.Create(path, 0644)
+		assert.NoError(t, err)
+		assert.NoError(t, file.Close())
+	}
+
+	walked := func(opts walk.Options) []string {
+		var paths []string
+		err := walk.Walk(memFs, "src", opts, func(path string) error {
+			paths = append(paths, path)
+			return nil
+		})
+		assert.NoError(t, err)
+		return paths
+	}
+
+	t.Run("should list all files except hidden ones in lexical order", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"src/README.md",
+			"src/cmd/app/app.go",
+			"src/main.go",
+			"src/vendor/lib/lib.go",
+		}, walked(walk.Options{}))
+	})
+
+	t.Run("should list hidden files if enabled", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"src/.env",
+			"src/.git/config",
+			"src/README.md",
+			"src/cmd/app/app.go",
+			"src/main.go",
+			"src/vendor/lib/lib.go",
+		}, walked(walk.Options{Hidden: true}))
+	})
+
+	t.Run("should filter files by include and exclude globs", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"src/cmd/app/app.go",
+			"src/main.go",
+		}, walked(walk.Options{
+			Include: []*glob.Glob{glob.MustCompile("*.go")},
+			Exclude: []*glob.Glob{glob.MustCompile("vendor/**")},
+		}))
+	})
+
+	t.Run("should not descend below max depth", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"src/README.md",
+			"src/main.go",
+		}, walked(walk.Options{MaxDepth: 1}))
+	})
+
+	t.Run("should return error if root is not a directory", func(t *testing.T) {
+		err := walk.Walk(memFs, "src/main.go", walk.Options{}, func(string) error { return nil })
+		assert.Error(t, err)
+	})
+}
+
+func TestWalk_Ignore(t *testing.T) {
+	memFs := fs.NewMemFs()
+	for path, content := range map[string]string{
+		"repo/.git/config":            "",
+		"repo/.git/info/exclude":      "local.txt\n",
+		"repo/.gitignore":             "*.log\nbuild/\n!keep.log\n",
+		"repo/.replace-text-ignore":   "fixtures/\n",
+		"repo/local.txt":              "",
+		"repo/app.log":                "",
+		"repo/keep.log":               "",
+		"repo/main.go":                "",
+		"repo/build/out.go":           "",
+		"repo/fixtures/data.txt":      "",
+		"repo/api/.ignore":            "!*.log\n/gen.go\n",
+		"repo/api/api.log":            "",
+		"repo/api/gen.go":             "",
+		"repo/api/v1/gen.go":          "",
+		"repo/docs/build":             "",
+		"repo/docs/nested/.gitignore": "/*.md\n",
+		"repo/docs/nested/a.md":       "",
+		"repo/docs/b.md":              "",
+	} {
+		assert.NoError(t, memFs.MkdirAll(filepath.Dir(path), 0755))
+		file, err := memFs.Create(path, 0644)
+		assert.NoError(t, err)
+		_, err = file.Write([]byte(content))
+		assert.NoError(t, err)
+		assert.NoError(t, file.Close())
+	}
+
+	walked := func(opts walk.Options) []string {
+		var paths []string
+		err := walk.Walk(memFs, "repo", opts, func(path string) error {
+			paths = append(paths, path)
+			return nil
+		})
+		assert.NoError(t, err)
+		return paths
+	}
+
+	t.Run("should skip files ignored by ignore files", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"repo/api/api.log",
+			"repo/api/v1/gen.go",
+			"repo/docs/b.md",
+			"repo/docs/build",
+			"repo/keep.log",
+			"repo/main.go",
+		}, walked(walk.Options{Ignore: true}))
+	})
+
+	t.Run("should skip the git directory even if hidden files are walked", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"repo/.gitignore",
+			"repo/.replace-text-ignore",
+			"repo/api/.ignore",
+			"repo/api/api.log",
+			"repo/api/v1/gen.go",
+			"repo/docs/b.md",
+			"repo/docs/build",
+			"repo/docs/nested/.gitignore",
+			"repo/keep.log",
+			"repo/main.go",
+		}, walked(walk.Options{Ignore: true, Hidden: true}))
+	})
+
+	t.Run("should walk ignored files if disabled", func(t *testing.T) {
+		assert.Len(t, walked(walk.Options{}), 12)
+	})
+}